@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"monkey/ast"
+	"monkey/astdump"
+	"monkey/lexer"
+	"monkey/parser"
+	"os"
+)
+
+const astUsage = "usage: monkey ast [--format=tree|sexpr|dot] <file>"
+
+// monkey ast <file>
+// ファイルを構文解析して、構文木を木・S式・Graphviz の DOT のどれかで出力する
+func runAST(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("ast", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, astUsage)
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", "tree", "output format: tree, sexpr or dot")
+
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	var dump func(program ast.Node) string
+	switch *format {
+	case "tree":
+		dump = astdump.Tree
+	case "sexpr":
+		dump = func(program ast.Node) string { return astdump.Sexpr(program) + "\n" }
+	case "dot":
+		dump = astdump.DOT
+	default:
+		fmt.Fprintf(stderr, "unknown format %q\n%s\n", *format, astUsage)
+		return exitUsage
+	}
+
+	filename := fs.Arg(0)
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitRuntimeError
+	}
+
+	p := parser.New(lexer.New(string(src), lexer.WithFilename(filename)))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) > 0 {
+		fmt.Fprint(stderr, errs.Render(string(src)))
+		return parseErrorCode(errs)
+	}
+
+	io.WriteString(stdout, dump(program))
+	return exitOK
+}
@@ -2,13 +2,19 @@ package ast
 
 import (
 	"bytes"
+	"fmt"
 	"monkey/token"
+	"reflect"
 	"strconv"
+	"strings"
+	"unicode"
 )
 
 type Node interface {
 	TokenLiteral() string // デバッグ用
 	String() string       // デバッグでASTノードを表示用
+	Pos() token.Position  // ノードの最初の文字の位置
+	End() token.Position  // ノードの最後の文字の直後の位置
 }
 
 // 文
@@ -42,6 +48,18 @@ func (p *Program) String() string {
 	}
 	return out.String()
 }
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+func (p *Program) End() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[len(p.Statements)-1].End()
+	}
+	return token.Position{}
+}
 
 // let statement
 type LetStatement struct {
@@ -55,6 +73,16 @@ func (ls *LetStatement) statementNode() {}
 func (ls *LetStatement) TokenLiteral() string {
 	return ls.Token.Literal
 }
+func (ls *LetStatement) Pos() token.Position { return ls.Token.Pos }
+func (ls *LetStatement) End() token.Position {
+	if ls.Value != nil {
+		return ls.Value.End()
+	}
+	if ls.Name != nil {
+		return ls.Name.End()
+	}
+	return ls.Token.End
+}
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 
@@ -80,6 +108,13 @@ func (rs *ReturnStatement) statementNode() {}
 func (rs *ReturnStatement) TokenLiteral() string {
 	return rs.Token.Literal
 }
+func (rs *ReturnStatement) Pos() token.Position { return rs.Token.Pos }
+func (rs *ReturnStatement) End() token.Position {
+	if rs.ReturnValue != nil {
+		return rs.ReturnValue.End()
+	}
+	return rs.Token.End
+}
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
 
@@ -103,6 +138,13 @@ func (es *ExpressionStatement) statementNode() {}
 func (es *ExpressionStatement) TokenLiteral() string {
 	return es.Token.Literal
 }
+func (es *ExpressionStatement) Pos() token.Position { return es.Token.Pos }
+func (es *ExpressionStatement) End() token.Position {
+	if es.Expression != nil {
+		return es.Expression.End()
+	}
+	return es.Token.End
+}
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
 		return es.Expression.String()
@@ -110,6 +152,77 @@ func (es *ExpressionStatement) String() string {
 	return ""
 }
 
+// && と ||
+// 左辺だけで結果が決まるときは右辺を評価しないので InfixExpression と分けている
+type LogicalExpression struct {
+	Token    token.Token // token.AND か token.OR
+	Left     Expression
+	Operator string
+	Right    Expression
+}
+
+func (le *LogicalExpression) expressionNode() {}
+func (le *LogicalExpression) TokenLiteral() string {
+	return le.Token.Literal
+}
+func (le *LogicalExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(le.Left.String())
+	out.WriteString(" " + le.Operator + " ")
+	out.WriteString(le.Right.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+func (le *LogicalExpression) Pos() token.Position {
+	if le.Left != nil {
+		return le.Left.Pos()
+	}
+	return le.Token.Pos
+}
+func (le *LogicalExpression) End() token.Position {
+	if le.Right != nil {
+		return le.Right.End()
+	}
+	return le.Token.End
+}
+
+// { } で囲まれた文の並び
+type BlockStatement struct {
+	Token      token.Token // token.LBRACE
+	Statements []Statement
+	RBrace     token.Token // token.RBRACE
+}
+
+func (bs *BlockStatement) statementNode() {}
+func (bs *BlockStatement) TokenLiteral() string {
+	return bs.Token.Literal
+}
+func (bs *BlockStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("{ ")
+	for _, s := range bs.Statements {
+		out.WriteString(s.String())
+		out.WriteString(" ")
+	}
+	out.WriteString("}")
+
+	return out.String()
+}
+func (bs *BlockStatement) Pos() token.Position { return bs.Token.Pos }
+func (bs *BlockStatement) End() token.Position {
+	if bs.RBrace.End.IsValid() {
+		return bs.RBrace.End
+	}
+	// } が無いまま終わった場合
+	if len(bs.Statements) > 0 {
+		return bs.Statements[len(bs.Statements)-1].End()
+	}
+	return bs.Token.End
+}
+
 // ===================
 
 // let で使う変数名
@@ -123,6 +236,8 @@ func (i *Identifier) expressionNode() {}
 func (i *Identifier) TokenLiteral() string {
 	return i.Token.Literal
 }
+func (i *Identifier) Pos() token.Position { return i.Token.Pos }
+func (i *Identifier) End() token.Position { return i.Token.End }
 func (i *Identifier) String() string {
 	return i.Value
 }
@@ -138,10 +253,61 @@ func (il *IntegerLiteral) TokenLiteral() string {
 	return il.Token.Literal
 	// Token.Literal は もともと全部string
 }
+func (il *IntegerLiteral) Pos() token.Position { return il.Token.Pos }
+func (il *IntegerLiteral) End() token.Position { return il.Token.End }
 func (il *IntegerLiteral) String() string {
 	return strconv.FormatInt(il.Value, 10)
 }
 
+// 小数
+type FloatLiteral struct {
+	Token token.Token // token.FLOAT
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode() {}
+func (fl *FloatLiteral) TokenLiteral() string {
+	return fl.Token.Literal
+}
+func (fl *FloatLiteral) String() string {
+	return FormatFloat(fl.Value)
+}
+func (fl *FloatLiteral) Pos() token.Position { return fl.Token.Pos }
+func (fl *FloatLiteral) End() token.Position { return fl.Token.End }
+
+// 文字列
+// Value はエスケープを解釈した後の中身
+type StringLiteral struct {
+	Token token.Token // token.STRING
+	Value string
+}
+
+func (sl *StringLiteral) expressionNode() {}
+func (sl *StringLiteral) TokenLiteral() string {
+	return sl.Token.Literal
+}
+func (sl *StringLiteral) String() string {
+	return Quote(sl.Value)
+}
+func (sl *StringLiteral) Pos() token.Position { return sl.Token.Pos }
+func (sl *StringLiteral) End() token.Position { return sl.Token.End }
+
+// 真偽値
+type Boolean struct {
+	Token token.Token // token.TRUE か token.FALSE
+	Value bool
+}
+
+func (b *Boolean) expressionNode() {}
+func (b *Boolean) TokenLiteral() string {
+	return b.Token.Literal
+}
+func (b *Boolean) String() string {
+	return b.Token.Literal
+}
+func (b *Boolean) Pos() token.Position { return b.Token.Pos }
+func (b *Boolean) End() token.Position { return b.Token.End }
+
 // 前置演算子
 type PrefixExpression struct {
 	Token    token.Token // 任意の前置きトークン
@@ -154,6 +320,13 @@ func (pe *PrefixExpression) TokenLiteral() string {
 	return pe.Token.Literal
 	// Token.Literal は もともと全部string
 }
+func (pe *PrefixExpression) Pos() token.Position { return pe.Token.Pos }
+func (pe *PrefixExpression) End() token.Position {
+	if pe.Right != nil {
+		return pe.Right.End()
+	}
+	return pe.Token.End
+}
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -177,6 +350,18 @@ func (ie *InfixExpression) TokenLiteral() string {
 	return ie.Token.Literal
 	// Token.Literal は もともと全部string
 }
+func (ie *InfixExpression) Pos() token.Position {
+	if ie.Left != nil {
+		return ie.Left.Pos()
+	}
+	return ie.Token.Pos
+}
+func (ie *InfixExpression) End() token.Position {
+	if ie.Right != nil {
+		return ie.Right.End()
+	}
+	return ie.Token.End
+}
 func (ie *InfixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -187,3 +372,315 @@ func (ie *InfixExpression) String() string {
 
 	return out.String()
 }
+
+// if (Condition) { Consequence } else { Alternative }
+// if は値を返すので式として扱う
+type IfExpression struct {
+	Token       token.Token // token.IF
+	Condition   Expression
+	Consequence *BlockStatement
+	Alternative *BlockStatement // else が無ければ nil
+}
+
+func (ie *IfExpression) expressionNode() {}
+func (ie *IfExpression) TokenLiteral() string {
+	return ie.Token.Literal
+}
+func (ie *IfExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("if (")
+	out.WriteString(ie.Condition.String())
+	out.WriteString(") ")
+	out.WriteString(ie.Consequence.String())
+
+	if ie.Alternative != nil {
+		out.WriteString(" else ")
+		out.WriteString(ie.Alternative.String())
+	}
+
+	return out.String()
+}
+func (ie *IfExpression) Pos() token.Position { return ie.Token.Pos }
+func (ie *IfExpression) End() token.Position {
+	if ie.Alternative != nil {
+		return ie.Alternative.End()
+	}
+	if ie.Consequence != nil {
+		return ie.Consequence.End()
+	}
+	return ie.Token.End
+}
+
+// fn(Parameters) { Body }
+type FunctionLiteral struct {
+	Token      token.Token // token.FUNCTION
+	Parameters []*Identifier
+	Body       *BlockStatement
+}
+
+func (fl *FunctionLiteral) expressionNode() {}
+func (fl *FunctionLiteral) TokenLiteral() string {
+	return fl.Token.Literal
+}
+func (fl *FunctionLiteral) String() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range fl.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString(fl.TokenLiteral())
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") ")
+	out.WriteString(fl.Body.String())
+
+	return out.String()
+}
+func (fl *FunctionLiteral) Pos() token.Position { return fl.Token.Pos }
+func (fl *FunctionLiteral) End() token.Position {
+	if fl.Body != nil {
+		return fl.Body.End()
+	}
+	return fl.Token.End
+}
+
+// Function(Arguments)
+// Function は変数名か関数リテラル
+type CallExpression struct {
+	Token     token.Token // token.LPAREN
+	Function  Expression
+	Arguments []Expression
+	RParen    token.Token // token.RPAREN
+}
+
+func (ce *CallExpression) expressionNode() {}
+func (ce *CallExpression) TokenLiteral() string {
+	return ce.Token.Literal
+}
+func (ce *CallExpression) String() string {
+	var out bytes.Buffer
+
+	args := []string{}
+	for _, a := range ce.Arguments {
+		args = append(args, a.String())
+	}
+
+	out.WriteString(ce.Function.String())
+	out.WriteString("(")
+	out.WriteString(strings.Join(args, ", "))
+	out.WriteString(")")
+
+	return out.String()
+}
+func (ce *CallExpression) Pos() token.Position {
+	if ce.Function != nil {
+		return ce.Function.Pos()
+	}
+	return ce.Token.Pos
+}
+func (ce *CallExpression) End() token.Position {
+	if ce.RParen.End.IsValid() {
+		return ce.RParen.End
+	}
+	return ce.Token.End
+}
+
+// [1, 2, 3]
+type ArrayLiteral struct {
+	Token    token.Token // token.LBRACKET
+	Elements []Expression
+	RBracket token.Token // token.RBRACKET
+}
+
+func (al *ArrayLiteral) expressionNode() {}
+func (al *ArrayLiteral) TokenLiteral() string {
+	return al.Token.Literal
+}
+func (al *ArrayLiteral) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range al.Elements {
+		elements = append(elements, el.String())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+func (al *ArrayLiteral) Pos() token.Position { return al.Token.Pos }
+func (al *ArrayLiteral) End() token.Position {
+	if al.RBracket.End.IsValid() {
+		return al.RBracket.End
+	}
+	return al.Token.End
+}
+
+// {"one": 1, "two": 2}
+// 書かれた順に String() できるように map ではなく slice で持つ
+type HashLiteral struct {
+	Token  token.Token // token.LBRACE
+	Pairs  []*HashPair
+	RBrace token.Token // token.RBRACE
+}
+
+// HashLiteral の Key: Value 1組
+type HashPair struct {
+	Key   Expression
+	Value Expression
+}
+
+func (hl *HashLiteral) expressionNode() {}
+func (hl *HashLiteral) TokenLiteral() string {
+	return hl.Token.Literal
+}
+func (hl *HashLiteral) String() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.Key.String()+": "+pair.Value.String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+func (hl *HashLiteral) Pos() token.Position { return hl.Token.Pos }
+func (hl *HashLiteral) End() token.Position {
+	if hl.RBrace.End.IsValid() {
+		return hl.RBrace.End
+	}
+	return hl.Token.End
+}
+
+// Left[Index]
+type IndexExpression struct {
+	Token    token.Token // token.LBRACKET
+	Left     Expression
+	Index    Expression
+	RBracket token.Token // token.RBRACKET
+}
+
+func (ie *IndexExpression) expressionNode() {}
+func (ie *IndexExpression) TokenLiteral() string {
+	return ie.Token.Literal
+}
+func (ie *IndexExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(ie.Left.String())
+	out.WriteString("[")
+	out.WriteString(ie.Index.String())
+	out.WriteString("])")
+
+	return out.String()
+}
+func (ie *IndexExpression) Pos() token.Position {
+	if ie.Left != nil {
+		return ie.Left.Pos()
+	}
+	return ie.Token.Pos
+}
+func (ie *IndexExpression) End() token.Position {
+	if ie.RBracket.End.IsValid() {
+		return ie.RBracket.End
+	}
+	return ie.Token.End
+}
+
+// ===================
+
+// パースに失敗した文の代わりに置く
+// From から To までが読み飛ばした範囲
+type BadStatement struct {
+	Token token.Token // 失敗した文の最初のトークン
+	From  token.Position
+	To    token.Position
+}
+
+func (bs *BadStatement) statementNode() {}
+func (bs *BadStatement) TokenLiteral() string {
+	return bs.Token.Literal
+}
+func (bs *BadStatement) String() string {
+	return "<bad statement>"
+}
+func (bs *BadStatement) Pos() token.Position { return bs.From }
+func (bs *BadStatement) End() token.Position { return bs.To }
+
+// パースに失敗した式の代わりに置く
+// nil の代わりに入れておくことで String() などが落ちないようにする
+type BadExpression struct {
+	Token token.Token // 失敗したところのトークン
+	From  token.Position
+	To    token.Position
+}
+
+func (be *BadExpression) expressionNode() {}
+func (be *BadExpression) TokenLiteral() string {
+	return be.Token.Literal
+}
+func (be *BadExpression) String() string {
+	return "<bad expression>"
+}
+func (be *BadExpression) Pos() token.Position { return be.From }
+func (be *BadExpression) End() token.Position { return be.To }
+
+// f を小数として表示する
+// 3.0 を 3 と書くと整数として読まれてしまうので .0 を付ける
+func FormatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
+// s を Monkey の文字列リテラルとして書ける形で " " で囲んで返す
+func Quote(s string) string {
+	var out bytes.Buffer
+
+	out.WriteByte('"')
+	for _, ch := range s {
+		switch ch {
+		case '"':
+			out.WriteString(`\"`)
+		case '\\':
+			out.WriteString(`\\`)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\t':
+			out.WriteString(`\t`)
+		case '\r':
+			out.WriteString(`\r`)
+		default:
+			if unicode.IsPrint(ch) {
+				out.WriteRune(ch)
+			} else {
+				out.WriteString(fmt.Sprintf(`\u{%X}`, ch))
+			}
+		}
+	}
+	out.WriteByte('"')
+
+	return out.String()
+}
+
+// interface が nil のときだけでなく、中身が nil のポインタのときも true
+// IfExpression.Alternative のような nil の子ノードを Node として渡したときに使う
+func IsNil(node Node) bool {
+	if node == nil {
+		return true
+	}
+	v := reflect.ValueOf(node)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
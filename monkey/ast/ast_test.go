@@ -22,7 +22,27 @@ func TestString(t *testing.T) {
 		},
 	}
 
-	if program.String() != "let myVat = anotherVar;" {
+	if program.String() != "let myVar = anotherVar;" {
 		t.Errorf("program.String() wrong. got=%q", program.String())
 	}
 }
+
+func TestIsNil(t *testing.T) {
+	var block *BlockStatement
+
+	tests := []struct {
+		node     Node
+		expected bool
+	}{
+		{nil, true},
+		{block, true},
+		{&BlockStatement{}, false},
+		{&Identifier{Value: "x"}, false},
+	}
+
+	for i, tt := range tests {
+		if got := IsNil(tt.node); got != tt.expected {
+			t.Errorf("tests[%d] - IsNil wrong. expected=%t, got=%t", i, tt.expected, got)
+		}
+	}
+}
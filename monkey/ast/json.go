@@ -0,0 +1,444 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// 構文木を JSON にする
+//
+// ノードはどれも "kind" (LetStatement, InfixExpression など型の名前)、
+// "pos" と "end" (ノード全体の範囲)、"token" を持ち、その後に種類ごとの子ノードや値が続く
+// 子ノードがないところは null になる
+//
+//	{"kind":"PrefixExpression","pos":{...},"end":{...},"token":{...},"operator":"-","right":{...}}
+func MarshalJSON(node Node) ([]byte, error) {
+	e := &encoder{}
+	v := e.toJSON(node)
+	if e.err != nil {
+		return nil, e.err
+	}
+	return json.Marshal(v)
+}
+
+// MarshalJSON で作った JSON から構文木を組み立て直す
+// "pos" と "end" は子ノードとトークンから分かるので読まない
+func UnmarshalJSON(data []byte) (Node, error) {
+	return decodeNode(data)
+}
+
+// キーを書いた順に出力する JSON オブジェクト
+// map だとキーが辞書順に並び替えられて "kind" が先頭に来ないため
+type object []field
+
+type field struct {
+	name  string
+	value interface{}
+}
+
+func (o object) MarshalJSON() ([]byte, error) {
+	var out bytes.Buffer
+	out.WriteString("{")
+	for i, f := range o {
+		if i > 0 {
+			out.WriteString(",")
+		}
+		name, _ := json.Marshal(f.name)
+		out.Write(name)
+		out.WriteString(":")
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		out.Write(value)
+	}
+	out.WriteString("}")
+	return out.Bytes(), nil
+}
+
+// JSON にできないノードがあれば、最初のものだけを覚えておく
+type encoder struct {
+	err error
+}
+
+func (e *encoder) toJSON(node Node) interface{} {
+	if IsNil(node) {
+		return nil
+	}
+
+	header := func(kind string) object {
+		return object{{"kind", kind}, {"pos", node.Pos()}, {"end", node.End()}}
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		return append(header("Program"),
+			field{"statements", e.statementsJSON(n.Statements)})
+	case *LetStatement:
+		return append(header("LetStatement"),
+			field{"token", n.Token}, field{"name", e.toJSON(n.Name)}, field{"value", e.toJSON(n.Value)})
+	case *ReturnStatement:
+		return append(header("ReturnStatement"),
+			field{"token", n.Token}, field{"returnValue", e.toJSON(n.ReturnValue)})
+	case *ExpressionStatement:
+		return append(header("ExpressionStatement"),
+			field{"token", n.Token}, field{"expression", e.toJSON(n.Expression)})
+	case *BlockStatement:
+		return append(header("BlockStatement"),
+			field{"token", n.Token}, field{"statements", e.statementsJSON(n.Statements)}, field{"rbrace", n.RBrace})
+	case *Identifier:
+		return append(header("Identifier"),
+			field{"token", n.Token}, field{"value", n.Value})
+	case *IntegerLiteral:
+		return append(header("IntegerLiteral"),
+			field{"token", n.Token}, field{"value", n.Value})
+	case *FloatLiteral:
+		return append(header("FloatLiteral"),
+			field{"token", n.Token}, field{"value", n.Value})
+	case *StringLiteral:
+		return append(header("StringLiteral"),
+			field{"token", n.Token}, field{"value", n.Value})
+	case *Boolean:
+		return append(header("Boolean"),
+			field{"token", n.Token}, field{"value", n.Value})
+	case *PrefixExpression:
+		return append(header("PrefixExpression"),
+			field{"token", n.Token}, field{"operator", n.Operator}, field{"right", e.toJSON(n.Right)})
+	case *InfixExpression:
+		return append(header("InfixExpression"),
+			field{"token", n.Token}, field{"left", e.toJSON(n.Left)}, field{"operator", n.Operator}, field{"right", e.toJSON(n.Right)})
+	case *LogicalExpression:
+		return append(header("LogicalExpression"),
+			field{"token", n.Token}, field{"left", e.toJSON(n.Left)}, field{"operator", n.Operator}, field{"right", e.toJSON(n.Right)})
+	case *IfExpression:
+		return append(header("IfExpression"),
+			field{"token", n.Token}, field{"condition", e.toJSON(n.Condition)},
+			field{"consequence", e.toJSON(n.Consequence)}, field{"alternative", e.toJSON(n.Alternative)})
+	case *FunctionLiteral:
+		params := []interface{}{}
+		for _, p := range n.Parameters {
+			params = append(params, e.toJSON(p))
+		}
+		return append(header("FunctionLiteral"),
+			field{"token", n.Token}, field{"parameters", params}, field{"body", e.toJSON(n.Body)})
+	case *CallExpression:
+		return append(header("CallExpression"),
+			field{"token", n.Token}, field{"function", e.toJSON(n.Function)},
+			field{"arguments", e.expressionsJSON(n.Arguments)}, field{"rparen", n.RParen})
+	case *ArrayLiteral:
+		return append(header("ArrayLiteral"),
+			field{"token", n.Token}, field{"elements", e.expressionsJSON(n.Elements)}, field{"rbracket", n.RBracket})
+	case *HashLiteral:
+		pairs := []interface{}{}
+		for _, pair := range n.Pairs {
+			pairs = append(pairs, object{{"key", e.toJSON(pair.Key)}, {"value", e.toJSON(pair.Value)}})
+		}
+		return append(header("HashLiteral"),
+			field{"token", n.Token}, field{"pairs", pairs}, field{"rbrace", n.RBrace})
+	case *IndexExpression:
+		return append(header("IndexExpression"),
+			field{"token", n.Token}, field{"left", e.toJSON(n.Left)}, field{"index", e.toJSON(n.Index)}, field{"rbracket", n.RBracket})
+	case *BadStatement:
+		return append(header("BadStatement"),
+			field{"token", n.Token}, field{"from", n.From}, field{"to", n.To})
+	case *BadExpression:
+		return append(header("BadExpression"),
+			field{"token", n.Token}, field{"from", n.From}, field{"to", n.To})
+	}
+
+	if e.err == nil {
+		e.err = fmt.Errorf("ast: cannot marshal node type %T", node)
+	}
+	return nil
+}
+
+func (e *encoder) statementsJSON(statements []Statement) []interface{} {
+	out := []interface{}{}
+	for _, s := range statements {
+		out = append(out, e.toJSON(s))
+	}
+	return out
+}
+
+func (e *encoder) expressionsJSON(exps []Expression) []interface{} {
+	out := []interface{}{}
+	for _, exp := range exps {
+		out = append(out, e.toJSON(exp))
+	}
+	return out
+}
+
+// JSON オブジェクト1つ分のフィールドを読む
+// 最初に起きたエラーだけを覚えておく
+type decoder struct {
+	kind   string
+	fields map[string]json.RawMessage
+	err    error
+}
+
+func decodeNode(data []byte) (Node, error) {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil, nil
+	}
+
+	d := &decoder{}
+	if err := json.Unmarshal(data, &d.fields); err != nil {
+		return nil, err
+	}
+	d.value("kind", &d.kind)
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	var node Node
+	switch d.kind {
+	case "Program":
+		node = &Program{Statements: d.statements("statements")}
+	case "LetStatement":
+		n := &LetStatement{}
+		d.value("token", &n.Token)
+		n.Name = d.identifier("name")
+		n.Value = d.expression("value")
+		node = n
+	case "ReturnStatement":
+		n := &ReturnStatement{}
+		d.value("token", &n.Token)
+		n.ReturnValue = d.expression("returnValue")
+		node = n
+	case "ExpressionStatement":
+		n := &ExpressionStatement{}
+		d.value("token", &n.Token)
+		n.Expression = d.expression("expression")
+		node = n
+	case "BlockStatement":
+		n := &BlockStatement{}
+		d.value("token", &n.Token)
+		n.Statements = d.statements("statements")
+		d.value("rbrace", &n.RBrace)
+		node = n
+	case "Identifier":
+		n := &Identifier{}
+		d.value("token", &n.Token)
+		d.value("value", &n.Value)
+		node = n
+	case "IntegerLiteral":
+		n := &IntegerLiteral{}
+		d.value("token", &n.Token)
+		d.value("value", &n.Value)
+		node = n
+	case "FloatLiteral":
+		n := &FloatLiteral{}
+		d.value("token", &n.Token)
+		d.value("value", &n.Value)
+		node = n
+	case "StringLiteral":
+		n := &StringLiteral{}
+		d.value("token", &n.Token)
+		d.value("value", &n.Value)
+		node = n
+	case "Boolean":
+		n := &Boolean{}
+		d.value("token", &n.Token)
+		d.value("value", &n.Value)
+		node = n
+	case "PrefixExpression":
+		n := &PrefixExpression{}
+		d.value("token", &n.Token)
+		d.value("operator", &n.Operator)
+		n.Right = d.expression("right")
+		node = n
+	case "InfixExpression":
+		n := &InfixExpression{}
+		d.value("token", &n.Token)
+		n.Left = d.expression("left")
+		d.value("operator", &n.Operator)
+		n.Right = d.expression("right")
+		node = n
+	case "LogicalExpression":
+		n := &LogicalExpression{}
+		d.value("token", &n.Token)
+		n.Left = d.expression("left")
+		d.value("operator", &n.Operator)
+		n.Right = d.expression("right")
+		node = n
+	case "IfExpression":
+		n := &IfExpression{}
+		d.value("token", &n.Token)
+		n.Condition = d.expression("condition")
+		n.Consequence = d.block("consequence")
+		n.Alternative = d.block("alternative")
+		node = n
+	case "FunctionLiteral":
+		n := &FunctionLiteral{Parameters: []*Identifier{}}
+		d.value("token", &n.Token)
+		for _, raw := range d.list("parameters") {
+			n.Parameters = append(n.Parameters, d.asIdentifier(d.decode(raw)))
+		}
+		n.Body = d.block("body")
+		node = n
+	case "CallExpression":
+		n := &CallExpression{}
+		d.value("token", &n.Token)
+		n.Function = d.expression("function")
+		n.Arguments = d.expressions("arguments")
+		d.value("rparen", &n.RParen)
+		node = n
+	case "ArrayLiteral":
+		n := &ArrayLiteral{}
+		d.value("token", &n.Token)
+		n.Elements = d.expressions("elements")
+		d.value("rbracket", &n.RBracket)
+		node = n
+	case "HashLiteral":
+		n := &HashLiteral{Pairs: []*HashPair{}}
+		d.value("token", &n.Token)
+		for _, raw := range d.list("pairs") {
+			pair := &decoder{kind: "HashPair"}
+			if err := json.Unmarshal(raw, &pair.fields); err != nil {
+				return nil, err
+			}
+			n.Pairs = append(n.Pairs, &HashPair{Key: pair.expression("key"), Value: pair.expression("value")})
+			if pair.err != nil {
+				return nil, pair.err
+			}
+		}
+		d.value("rbrace", &n.RBrace)
+		node = n
+	case "IndexExpression":
+		n := &IndexExpression{}
+		d.value("token", &n.Token)
+		n.Left = d.expression("left")
+		n.Index = d.expression("index")
+		d.value("rbracket", &n.RBracket)
+		node = n
+	case "BadStatement":
+		n := &BadStatement{}
+		d.value("token", &n.Token)
+		d.value("from", &n.From)
+		d.value("to", &n.To)
+		node = n
+	case "BadExpression":
+		n := &BadExpression{}
+		d.value("token", &n.Token)
+		d.value("from", &n.From)
+		d.value("to", &n.To)
+		node = n
+	default:
+		return nil, fmt.Errorf("ast: unknown node kind %q", d.kind)
+	}
+
+	if d.err != nil {
+		return nil, d.err
+	}
+	return node, nil
+}
+
+// フィールドをそのまま読む (無ければゼロ値のまま)
+func (d *decoder) value(name string, v interface{}) {
+	raw, ok := d.fields[name]
+	if !ok || d.err != nil {
+		return
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		d.err = fmt.Errorf("ast: %s.%s: %w", d.kind, name, err)
+	}
+}
+
+func (d *decoder) decode(raw json.RawMessage) Node {
+	if d.err != nil {
+		return nil
+	}
+	node, err := decodeNode(raw)
+	if err != nil {
+		d.err = err
+	}
+	return node
+}
+
+func (d *decoder) node(name string) Node {
+	raw, ok := d.fields[name]
+	if !ok {
+		return nil
+	}
+	return d.decode(raw)
+}
+
+func (d *decoder) list(name string) []json.RawMessage {
+	var list []json.RawMessage
+	d.value(name, &list)
+	return list
+}
+
+func (d *decoder) expression(name string) Expression {
+	return d.asExpression(d.node(name))
+}
+
+func (d *decoder) identifier(name string) *Identifier {
+	return d.asIdentifier(d.node(name))
+}
+
+func (d *decoder) block(name string) *BlockStatement {
+	node := d.node(name)
+	if node == nil {
+		return nil
+	}
+	block, ok := node.(*BlockStatement)
+	if !ok {
+		d.fail(node, "a block")
+	}
+	return block
+}
+
+func (d *decoder) statements(name string) []Statement {
+	statements := []Statement{}
+	for _, raw := range d.list(name) {
+		node := d.decode(raw)
+		if node == nil {
+			continue
+		}
+		statement, ok := node.(Statement)
+		if !ok {
+			d.fail(node, "a statement")
+			continue
+		}
+		statements = append(statements, statement)
+	}
+	return statements
+}
+
+func (d *decoder) expressions(name string) []Expression {
+	exps := []Expression{}
+	for _, raw := range d.list(name) {
+		exps = append(exps, d.asExpression(d.decode(raw)))
+	}
+	return exps
+}
+
+func (d *decoder) asExpression(node Node) Expression {
+	if node == nil {
+		return nil
+	}
+	exp, ok := node.(Expression)
+	if !ok {
+		d.fail(node, "an expression")
+	}
+	return exp
+}
+
+func (d *decoder) asIdentifier(node Node) *Identifier {
+	if node == nil {
+		return nil
+	}
+	ident, ok := node.(*Identifier)
+	if !ok {
+		d.fail(node, "an identifier")
+	}
+	return ident
+}
+
+func (d *decoder) fail(node Node, want string) {
+	if d.err == nil {
+		d.err = fmt.Errorf("ast: %s: %T is not %s", d.kind, node, want)
+	}
+}
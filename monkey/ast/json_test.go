@@ -0,0 +1,101 @@
+package ast
+
+import (
+	"monkey/token"
+	"strings"
+	"testing"
+)
+
+func TestMarshalJSONSchema(t *testing.T) {
+	// -x
+	node := &PrefixExpression{
+		Token:    token.Token{Type: token.MINUS, Literal: "-", Pos: token.Position{Offset: 0, Line: 1, Column: 1}, End: token.Position{Offset: 1, Line: 1, Column: 2}},
+		Operator: "-",
+		Right: &Identifier{
+			Token: token.Token{Type: token.IDENT, Literal: "x", Pos: token.Position{Offset: 1, Line: 1, Column: 2}, End: token.Position{Offset: 2, Line: 1, Column: 3}},
+			Value: "x",
+		},
+	}
+
+	data, err := MarshalJSON(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"kind":"PrefixExpression",` +
+		`"pos":{"offset":0,"line":1,"column":1},"end":{"offset":2,"line":1,"column":3},` +
+		`"token":{"type":"-","literal":"-","pos":{"offset":0,"line":1,"column":1},"end":{"offset":1,"line":1,"column":2}},` +
+		`"operator":"-",` +
+		`"right":{"kind":"Identifier",` +
+		`"pos":{"offset":1,"line":1,"column":2},"end":{"offset":2,"line":1,"column":3},` +
+		`"token":{"type":"IDENT","literal":"x","pos":{"offset":1,"line":1,"column":2},"end":{"offset":2,"line":1,"column":3}},` +
+		`"value":"x"}}`
+	if string(data) != expected {
+		t.Errorf("MarshalJSON wrong.\nexpected=%s\ngot=%s", expected, data)
+	}
+}
+
+func TestMarshalJSONNilChildren(t *testing.T) {
+	data, err := MarshalJSON(&IfExpression{Condition: &Boolean{Value: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"consequence":null,"alternative":null`) {
+		t.Errorf("nil children should be null. got=%s", data)
+	}
+
+	node, err := UnmarshalJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ie, ok := node.(*IfExpression)
+	if !ok {
+		t.Fatalf("node is not *IfExpression. got=%T", node)
+	}
+	if ie.Consequence != nil || ie.Alternative != nil {
+		t.Errorf("nil children should stay nil. got=%+v", ie)
+	}
+}
+
+func TestUnmarshalJSONErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"kind":"Macro"}`, `ast: unknown node kind "Macro"`},
+		{`{"value":1}`, `ast: unknown node kind ""`},
+		{`{"kind":"IntegerLiteral","value":"one"}`, "ast: IntegerLiteral.value: json: cannot unmarshal string"},
+		{`{"kind":"PrefixExpression","right":{"kind":"LetStatement"}}`, "ast: PrefixExpression: *ast.LetStatement is not an expression"},
+		{`{"kind":"Program","statements":[{"kind":"Identifier"}]}`, "ast: Program: *ast.Identifier is not a statement"},
+		{`{"kind":"FunctionLiteral","parameters":[{"kind":"Boolean"}]}`, "ast: FunctionLiteral: *ast.Boolean is not an identifier"},
+		{`[1]`, "json: cannot unmarshal array"},
+	}
+
+	for _, tt := range tests {
+		_, err := UnmarshalJSON([]byte(tt.input))
+		if err == nil {
+			t.Errorf("UnmarshalJSON(%s) should fail", tt.input)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), tt.expected) {
+			t.Errorf("UnmarshalJSON(%s) error wrong.\nexpected=%q\ngot=%q", tt.input, tt.expected, err.Error())
+		}
+	}
+}
+
+// MarshalJSON が知らない種類のノード
+type unknownNode struct{ Identifier }
+
+func TestMarshalJSONUnknownNode(t *testing.T) {
+	node := &ExpressionStatement{Expression: &unknownNode{}}
+
+	_, err := MarshalJSON(node)
+	if err == nil {
+		t.Fatal("MarshalJSON should fail for an unknown node type")
+	}
+
+	expected := "ast: cannot marshal node type *ast.unknownNode"
+	if err.Error() != expected {
+		t.Errorf("error wrong. expected=%q, got=%q", expected, err.Error())
+	}
+}
@@ -0,0 +1,145 @@
+package ast
+
+// ノードを受け取って、置き換えるノードを返す関数
+// そのままでよければ受け取ったノードを返す
+type ModifierFunc func(Node) Node
+
+// 構文木を書き換える
+// 子ノードを先に Modify で置き換えてから、最後にそのノード自身を modifier に渡す
+// 置き換えたノードの型が合わないとき (式の場所に文を返したなど) や nil のときは、元の子ノードのまま残す
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch node := node.(type) {
+	case *Program:
+		for i, statement := range node.Statements {
+			node.Statements[i] = modifyStatement(statement, modifier)
+		}
+
+	case *LetStatement:
+		if node.Name != nil {
+			node.Name = modifyIdentifier(node.Name, modifier)
+		}
+		if node.Value != nil {
+			node.Value = modifyExpression(node.Value, modifier)
+		}
+
+	case *ReturnStatement:
+		if node.ReturnValue != nil {
+			node.ReturnValue = modifyExpression(node.ReturnValue, modifier)
+		}
+
+	case *ExpressionStatement:
+		if node.Expression != nil {
+			node.Expression = modifyExpression(node.Expression, modifier)
+		}
+
+	case *BlockStatement:
+		for i, statement := range node.Statements {
+			node.Statements[i] = modifyStatement(statement, modifier)
+		}
+
+	case *PrefixExpression:
+		if node.Right != nil {
+			node.Right = modifyExpression(node.Right, modifier)
+		}
+
+	case *InfixExpression:
+		if node.Left != nil {
+			node.Left = modifyExpression(node.Left, modifier)
+		}
+		if node.Right != nil {
+			node.Right = modifyExpression(node.Right, modifier)
+		}
+
+	case *LogicalExpression:
+		if node.Left != nil {
+			node.Left = modifyExpression(node.Left, modifier)
+		}
+		if node.Right != nil {
+			node.Right = modifyExpression(node.Right, modifier)
+		}
+
+	case *IfExpression:
+		if node.Condition != nil {
+			node.Condition = modifyExpression(node.Condition, modifier)
+		}
+		if node.Consequence != nil {
+			node.Consequence = modifyBlock(node.Consequence, modifier)
+		}
+		if node.Alternative != nil {
+			node.Alternative = modifyBlock(node.Alternative, modifier)
+		}
+
+	case *FunctionLiteral:
+		for i, parameter := range node.Parameters {
+			node.Parameters[i] = modifyIdentifier(parameter, modifier)
+		}
+		if node.Body != nil {
+			node.Body = modifyBlock(node.Body, modifier)
+		}
+
+	case *CallExpression:
+		if node.Function != nil {
+			node.Function = modifyExpression(node.Function, modifier)
+		}
+		modifyExpressions(node.Arguments, modifier)
+
+	case *ArrayLiteral:
+		modifyExpressions(node.Elements, modifier)
+
+	case *HashLiteral:
+		for _, pair := range node.Pairs {
+			if pair.Key != nil {
+				pair.Key = modifyExpression(pair.Key, modifier)
+			}
+			if pair.Value != nil {
+				pair.Value = modifyExpression(pair.Value, modifier)
+			}
+		}
+
+	case *IndexExpression:
+		if node.Left != nil {
+			node.Left = modifyExpression(node.Left, modifier)
+		}
+		if node.Index != nil {
+			node.Index = modifyExpression(node.Index, modifier)
+		}
+	}
+
+	return modifier(node)
+}
+
+func modifyExpression(e Expression, modifier ModifierFunc) Expression {
+	if modified, ok := Modify(e, modifier).(Expression); ok && !IsNil(modified) {
+		return modified
+	}
+	return e
+}
+
+func modifyStatement(s Statement, modifier ModifierFunc) Statement {
+	if modified, ok := Modify(s, modifier).(Statement); ok && !IsNil(modified) {
+		return modified
+	}
+	return s
+}
+
+func modifyIdentifier(ident *Identifier, modifier ModifierFunc) *Identifier {
+	if modified, ok := Modify(ident, modifier).(*Identifier); ok && modified != nil {
+		return modified
+	}
+	return ident
+}
+
+func modifyBlock(block *BlockStatement, modifier ModifierFunc) *BlockStatement {
+	if modified, ok := Modify(block, modifier).(*BlockStatement); ok && modified != nil {
+		return modified
+	}
+	return block
+}
+
+func modifyExpressions(exps []Expression, modifier ModifierFunc) {
+	for i, e := range exps {
+		if e != nil {
+			exps[i] = modifyExpression(e, modifier)
+		}
+	}
+}
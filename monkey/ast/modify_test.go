@@ -0,0 +1,221 @@
+package ast
+
+import (
+	"monkey/token"
+	"reflect"
+	"testing"
+)
+
+func TestModify(t *testing.T) {
+	one := func() Expression { return &IntegerLiteral{Value: 1} }
+	two := func() Expression { return &IntegerLiteral{Value: 2} }
+
+	turnOneIntoTwo := func(node Node) Node {
+		integer, ok := node.(*IntegerLiteral)
+		if !ok {
+			return node
+		}
+
+		if integer.Value != 1 {
+			return node
+		}
+
+		integer.Value = 2
+		return integer
+	}
+
+	tests := []struct {
+		input    Node
+		expected Node
+	}{
+		{
+			one(),
+			two(),
+		},
+		{
+			&Program{
+				Statements: []Statement{
+					&ExpressionStatement{Expression: one()},
+				},
+			},
+			&Program{
+				Statements: []Statement{
+					&ExpressionStatement{Expression: two()},
+				},
+			},
+		},
+		{
+			&InfixExpression{Left: one(), Operator: "+", Right: two()},
+			&InfixExpression{Left: two(), Operator: "+", Right: two()},
+		},
+		{
+			&InfixExpression{Left: two(), Operator: "+", Right: one()},
+			&InfixExpression{Left: two(), Operator: "+", Right: two()},
+		},
+		{
+			&LogicalExpression{Left: one(), Operator: "&&", Right: one()},
+			&LogicalExpression{Left: two(), Operator: "&&", Right: two()},
+		},
+		{
+			&PrefixExpression{Operator: "-", Right: one()},
+			&PrefixExpression{Operator: "-", Right: two()},
+		},
+		{
+			&IndexExpression{Left: one(), Index: one()},
+			&IndexExpression{Left: two(), Index: two()},
+		},
+		{
+			&IfExpression{
+				Condition: one(),
+				Consequence: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: one()},
+					},
+				},
+				Alternative: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: one()},
+					},
+				},
+			},
+			&IfExpression{
+				Condition: two(),
+				Consequence: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: two()},
+					},
+				},
+				Alternative: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: two()},
+					},
+				},
+			},
+		},
+		{
+			&ReturnStatement{ReturnValue: one()},
+			&ReturnStatement{ReturnValue: two()},
+		},
+		{
+			&LetStatement{Value: one()},
+			&LetStatement{Value: two()},
+		},
+		{
+			&FunctionLiteral{
+				Parameters: []*Identifier{},
+				Body: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: one()},
+					},
+				},
+			},
+			&FunctionLiteral{
+				Parameters: []*Identifier{},
+				Body: &BlockStatement{
+					Statements: []Statement{
+						&ExpressionStatement{Expression: two()},
+					},
+				},
+			},
+		},
+		{
+			&CallExpression{Function: one(), Arguments: []Expression{one(), two()}},
+			&CallExpression{Function: two(), Arguments: []Expression{two(), two()}},
+		},
+		{
+			&ArrayLiteral{Elements: []Expression{one(), one()}},
+			&ArrayLiteral{Elements: []Expression{two(), two()}},
+		},
+		{
+			&HashLiteral{Pairs: []*HashPair{{Key: one(), Value: one()}}},
+			&HashLiteral{Pairs: []*HashPair{{Key: two(), Value: two()}}},
+		},
+	}
+
+	for _, tt := range tests {
+		modified := Modify(tt.input, turnOneIntoTwo)
+
+		if !reflect.DeepEqual(modified, tt.expected) {
+			t.Errorf("not equal. got=%#v, want=%#v", modified, tt.expected)
+		}
+	}
+}
+
+func TestModifyReplacesNodes(t *testing.T) {
+	// 識別子 x を、ノードごと別の式に置き換える
+	input := &Program{
+		Statements: []Statement{
+			&LetStatement{
+				Token: token.Token{Type: token.LET, Literal: "let"},
+				Name:  &Identifier{Value: "y"},
+				Value: &InfixExpression{Left: &Identifier{Value: "x"}, Operator: "*", Right: &Identifier{Value: "x"}},
+			},
+		},
+	}
+
+	modified := Modify(input, func(node Node) Node {
+		if ident, ok := node.(*Identifier); ok && ident.Value == "x" {
+			return &PrefixExpression{Operator: "-", Right: &IntegerLiteral{Value: 3}}
+		}
+		return node
+	})
+
+	expected := "let y = ((-3) * (-3));"
+	if modified.String() != expected {
+		t.Errorf("modified wrong. expected=%q, got=%q", expected, modified.String())
+	}
+}
+
+func TestModifyKeepsChildOnTypeMismatch(t *testing.T) {
+	// 式や識別子を文に置き換えようとしても、型が合わない場所では元のまま
+	toStatement := func(node Node) Node {
+		switch node.(type) {
+		case *Identifier, *IntegerLiteral:
+			return &ExpressionStatement{}
+		}
+		return node
+	}
+
+	input := &Program{
+		Statements: []Statement{
+			&LetStatement{
+				Token: token.Token{Type: token.LET, Literal: "let"},
+				Name:  &Identifier{Value: "x"},
+				Value: &IntegerLiteral{Token: token.Token{Literal: "1"}, Value: 1},
+			},
+			&ReturnStatement{
+				Token:       token.Token{Type: token.RETURN, Literal: "return"},
+				ReturnValue: &Identifier{Value: "x"},
+			},
+		},
+	}
+
+	modified := Modify(input, toStatement)
+
+	expected := "let x = 1;return x;"
+	if modified.String() != expected {
+		t.Errorf("modified wrong. expected=%q, got=%q", expected, modified.String())
+	}
+
+	// 文の場所に式を返したときや、nil を返したときも元のまま
+	toNil := func(node Node) Node {
+		switch node.(type) {
+		case *LetStatement:
+			return &Identifier{Value: "y"}
+		case *ReturnStatement:
+			return nil
+		case *Identifier:
+			var ident *Identifier
+			return ident
+		}
+		return node
+	}
+
+	modified = Modify(input, toNil)
+	if modified.String() != expected {
+		t.Errorf("modified wrong. expected=%q, got=%q", expected, modified.String())
+	}
+
+	// たどっても落ちない
+	Inspect(modified, func(Node) bool { return true })
+}
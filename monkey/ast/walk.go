@@ -0,0 +1,147 @@
+package ast
+
+import "fmt"
+
+// 構文木をたどるときに、ノードごとに呼ばれる
+// go/ast と同じく、Visit が nil でない w を返したら子ノードを w でたどり、最後に w.Visit(nil) を呼ぶ
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// 構文木を深さ優先でたどる
+// 子ノードはソースコードに現れる順に訪れる。nil の子ノードは飛ばす
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		for _, s := range n.Statements {
+			Walk(v, s)
+		}
+
+	case *LetStatement:
+		if n.Name != nil {
+			Walk(v, n.Name)
+		}
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+
+	case *ReturnStatement:
+		if n.ReturnValue != nil {
+			Walk(v, n.ReturnValue)
+		}
+
+	case *ExpressionStatement:
+		if n.Expression != nil {
+			Walk(v, n.Expression)
+		}
+
+	case *BlockStatement:
+		for _, s := range n.Statements {
+			Walk(v, s)
+		}
+
+	case *Identifier, *IntegerLiteral, *FloatLiteral, *StringLiteral, *Boolean,
+		*BadStatement, *BadExpression:
+		// 子ノードはない
+
+	case *PrefixExpression:
+		if n.Right != nil {
+			Walk(v, n.Right)
+		}
+
+	case *InfixExpression:
+		if n.Left != nil {
+			Walk(v, n.Left)
+		}
+		if n.Right != nil {
+			Walk(v, n.Right)
+		}
+
+	case *LogicalExpression:
+		if n.Left != nil {
+			Walk(v, n.Left)
+		}
+		if n.Right != nil {
+			Walk(v, n.Right)
+		}
+
+	case *IfExpression:
+		if n.Condition != nil {
+			Walk(v, n.Condition)
+		}
+		if n.Consequence != nil {
+			Walk(v, n.Consequence)
+		}
+		if n.Alternative != nil {
+			Walk(v, n.Alternative)
+		}
+
+	case *FunctionLiteral:
+		for _, p := range n.Parameters {
+			Walk(v, p)
+		}
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+
+	case *CallExpression:
+		if n.Function != nil {
+			Walk(v, n.Function)
+		}
+		walkExpressions(v, n.Arguments)
+
+	case *ArrayLiteral:
+		walkExpressions(v, n.Elements)
+
+	case *HashLiteral:
+		for _, pair := range n.Pairs {
+			if pair.Key != nil {
+				Walk(v, pair.Key)
+			}
+			if pair.Value != nil {
+				Walk(v, pair.Value)
+			}
+		}
+
+	case *IndexExpression:
+		if n.Left != nil {
+			Walk(v, n.Left)
+		}
+		if n.Index != nil {
+			Walk(v, n.Index)
+		}
+
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+func walkExpressions(v Visitor, exps []Expression) {
+	for _, e := range exps {
+		if e != nil {
+			Walk(v, e)
+		}
+	}
+}
+
+// 関数を Visitor として使えるようにする
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// 構文木を深さ優先でたどって、ノードごとに f(node) を呼ぶ
+// f が false を返したらそのノードの子はたどらない。子をたどり終えたら f(nil) を呼ぶ
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
@@ -0,0 +1,147 @@
+package ast_test
+
+import (
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"reflect"
+	"testing"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program
+}
+
+// 識別子の数を数える Visitor
+type identCounter struct {
+	count int
+}
+
+func (c *identCounter) Visit(node ast.Node) ast.Visitor {
+	if _, ok := node.(*ast.Identifier); ok {
+		c.count++
+	}
+	return c
+}
+
+func TestWalkCountsIdentifiers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int
+	}{
+		{"let x = 1;", 1},
+		{"let add = fn(a, b) { return a + b; }; add(x, y);", 8},
+		{"if (a < b) { a } else { -b }", 4},
+		{"[a, b][i]", 3},
+		{`{"k": v, k2: [w]}`, 3},
+		{"!a && b || c(d)", 4},
+		{"1 + 2; \"s\"; true; 1.5", 0},
+	}
+
+	for _, tt := range tests {
+		c := &identCounter{}
+		ast.Walk(c, parse(t, tt.input))
+
+		if c.count != tt.expected {
+			t.Errorf("identifiers in %q wrong. expected=%d, got=%d", tt.input, tt.expected, c.count)
+		}
+	}
+}
+
+func TestInspectCollectsIntegers(t *testing.T) {
+	input := `
+let a = 1 + 2 * 3;
+let f = fn(x) { if (x > 4) { return [5, 6][0]; } else { x - 7 } };
+f({8: 9}[8]);
+`
+	program := parse(t, input)
+
+	var got []int64
+	ast.Inspect(program, func(node ast.Node) bool {
+		if il, ok := node.(*ast.IntegerLiteral); ok {
+			got = append(got, il.Value)
+		}
+		return true
+	})
+
+	expected := []int64{1, 2, 3, 4, 5, 6, 0, 7, 8, 9, 8}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("integer literals wrong. expected=%v, got=%v", expected, got)
+	}
+}
+
+func TestInspectSkipsChildren(t *testing.T) {
+	program := parse(t, "let f = fn(x) { 1 }; 2; fn() { 3 }")
+
+	// 関数の中には入らない
+	var got []int64
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FunctionLiteral:
+			return false
+		case *ast.IntegerLiteral:
+			got = append(got, node.Value)
+		}
+		return true
+	})
+
+	if !reflect.DeepEqual(got, []int64{2}) {
+		t.Errorf("integer literals wrong. expected=[2], got=%v", got)
+	}
+}
+
+func TestInspectCallsNilAfterChildren(t *testing.T) {
+	program := parse(t, "-x")
+
+	// 入ったノードと出たこと (nil) を順に記録する
+	var got []string
+	ast.Inspect(program, func(node ast.Node) bool {
+		if node == nil {
+			got = append(got, "end")
+		} else {
+			got = append(got, reflect.TypeOf(node).Elem().Name())
+		}
+		return true
+	})
+
+	expected := []string{
+		"Program", "ExpressionStatement", "PrefixExpression", "Identifier",
+		"end", "end", "end", "end",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("visit order wrong.\nexpected=%v\ngot=%v", expected, got)
+	}
+}
+
+func TestWalkSkipsNilChildren(t *testing.T) {
+	nodes := []ast.Node{
+		&ast.LetStatement{},
+		&ast.ReturnStatement{},
+		&ast.ExpressionStatement{},
+		&ast.IfExpression{},
+		&ast.FunctionLiteral{},
+		&ast.CallExpression{Arguments: []ast.Expression{nil}},
+		&ast.HashLiteral{Pairs: []*ast.HashPair{{}}},
+		&ast.IndexExpression{},
+	}
+
+	for _, node := range nodes {
+		count := 0
+		ast.Inspect(node, func(n ast.Node) bool {
+			if n != nil {
+				count++
+			}
+			return true
+		})
+
+		if count != 1 {
+			t.Errorf("%T: visited %d nodes, expected only the node itself", node, count)
+		}
+	}
+}
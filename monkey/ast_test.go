@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestASTFormats(t *testing.T) {
+	path := writeSource(t, "let x = 1 + 2;")
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{path}, "Program\n└─ LetStatement\n   ├─ Name: Identifier x\n   └─ Value: InfixExpression +\n" +
+			"      ├─ Left: IntegerLiteral 1\n      └─ Right: IntegerLiteral 2\n"},
+		{[]string{"--format=sexpr", path}, "(let x (+ 1 2))\n"},
+		{[]string{"--format=dot", path}, "digraph AST {\n"},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		code := runAST(tt.args, &stdout, &stderr)
+		if code != exitOK {
+			t.Fatalf("runAST(%q) exit code wrong. expected=%d, got=%d (%s)", tt.args, exitOK, code, stderr.String())
+		}
+
+		if !strings.HasPrefix(stdout.String(), tt.expected) {
+			t.Errorf("runAST(%q) output wrong.\nexpected=%q\ngot=%q", tt.args, tt.expected, stdout.String())
+		}
+	}
+}
+
+func TestASTErrors(t *testing.T) {
+	tests := []struct {
+		args           []string
+		expectedCode   int
+		expectedStderr string
+	}{
+		{[]string{writeSource(t, "let = 1;")}, exitParseError, "expected next token to be IDENT"},
+		{[]string{writeSource(t, `"abc`)}, exitLexError, "string literal not terminated"},
+		{[]string{"--format=png", "a.mk"}, exitUsage, `unknown format "png"`},
+		{[]string{}, exitUsage, "usage: monkey ast"},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		code := runAST(tt.args, &stdout, &stderr)
+		if code != tt.expectedCode {
+			t.Errorf("runAST(%q) exit code wrong. expected=%d, got=%d", tt.args, tt.expectedCode, code)
+		}
+
+		if !strings.Contains(stderr.String(), tt.expectedStderr) {
+			t.Errorf("runAST(%q) stderr wrong. expected to contain %q, got=%q", tt.args, tt.expectedStderr, stderr.String())
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package astdump
+
+import (
+	"bytes"
+	"fmt"
+	"monkey/ast"
+	"strings"
+)
+
+// 構文木を Graphviz の DOT 形式で書き出す
+// dot -Tsvg などで図にできる。辺のラベルは親のどのフィールドかを表す
+func DOT(node ast.Node) string {
+	var out bytes.Buffer
+	out.WriteString("digraph AST {\n")
+	out.WriteString("\tnode [shape=box, fontname=\"monospace\"];\n")
+
+	if !ast.IsNil(node) {
+		id := 0
+		writeDOT(&out, node, &id)
+	}
+
+	out.WriteString("}\n")
+	return out.String()
+}
+
+// ノードを n0, n1, ... と番号で呼び、親から子へ辺を引く
+// 自分の番号を返す
+func writeDOT(out *bytes.Buffer, node ast.Node, id *int) int {
+	self := *id
+	*id++
+	fmt.Fprintf(out, "\tn%d [label=%s];\n", self, dotQuote(label(node)))
+
+	for _, c := range children(node) {
+		child := writeDOT(out, c.node, id)
+		if c.name == "" {
+			fmt.Fprintf(out, "\tn%d -> n%d;\n", self, child)
+		} else {
+			fmt.Fprintf(out, "\tn%d -> n%d [label=%s];\n", self, child, dotQuote(c.name))
+		}
+	}
+
+	return self
+}
+
+// DOT の文字列として " で囲む
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
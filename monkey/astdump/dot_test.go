@@ -0,0 +1,34 @@
+package astdump
+
+import "testing"
+
+func TestDOT(t *testing.T) {
+	expected := `digraph AST {
+	node [shape=box, fontname="monospace"];
+	n0 [label="Program"];
+	n1 [label="ExpressionStatement"];
+	n2 [label="InfixExpression +"];
+	n3 [label="StringLiteral \"a\\n\""];
+	n2 -> n3 [label="Left"];
+	n4 [label="InfixExpression *"];
+	n5 [label="Identifier b"];
+	n4 -> n5 [label="Left"];
+	n6 [label="IntegerLiteral 2"];
+	n4 -> n6 [label="Right"];
+	n2 -> n4 [label="Right"];
+	n1 -> n2 [label="Expression"];
+	n0 -> n1;
+}
+`
+	got := DOT(parse(t, `"a\n" + b * 2`))
+	if got != expected {
+		t.Errorf("DOT wrong.\nexpected=\n%s\ngot=\n%s", expected, got)
+	}
+}
+
+func TestDOTEmpty(t *testing.T) {
+	expected := "digraph AST {\n\tnode [shape=box, fontname=\"monospace\"];\n}\n"
+	if got := DOT(nil); got != expected {
+		t.Errorf("DOT wrong.\nexpected=%q\ngot=%q", expected, got)
+	}
+}
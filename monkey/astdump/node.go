@@ -0,0 +1,105 @@
+package astdump
+
+import (
+	"fmt"
+	"monkey/ast"
+	"strconv"
+)
+
+// 名前付きの子ノード
+// 名前は Left や Arguments[0] のように、親のどのフィールドかを表す
+type child struct {
+	name string
+	node ast.Node
+}
+
+// ノードの種類と、あれば値や演算子
+func label(node ast.Node) string {
+	switch node := node.(type) {
+	case *ast.Identifier:
+		return "Identifier " + node.Value
+	case *ast.IntegerLiteral:
+		return "IntegerLiteral " + strconv.FormatInt(node.Value, 10)
+	case *ast.FloatLiteral:
+		return "FloatLiteral " + ast.FormatFloat(node.Value)
+	case *ast.StringLiteral:
+		return "StringLiteral " + ast.Quote(node.Value)
+	case *ast.Boolean:
+		return "Boolean " + strconv.FormatBool(node.Value)
+	case *ast.PrefixExpression:
+		return "PrefixExpression " + node.Operator
+	case *ast.InfixExpression:
+		return "InfixExpression " + node.Operator
+	case *ast.LogicalExpression:
+		return "LogicalExpression " + node.Operator
+	}
+	return fmt.Sprintf("%T", node)[len("*ast."):]
+}
+
+// 子ノードをソースコードに現れる順に返す (nil のものは除く)
+// 文の並びには名前を付けない
+func children(node ast.Node) []child {
+	var out []child
+	add := func(name string, n ast.Node) {
+		if !ast.IsNil(n) {
+			out = append(out, child{name, n})
+		}
+	}
+	addList := func(name string, nodes []ast.Node) {
+		for i, n := range nodes {
+			add(fmt.Sprintf("%s[%d]", name, i), n)
+		}
+	}
+
+	switch node := node.(type) {
+	case *ast.Program:
+		for _, s := range node.Statements {
+			add("", s)
+		}
+	case *ast.BlockStatement:
+		for _, s := range node.Statements {
+			add("", s)
+		}
+	case *ast.LetStatement:
+		add("Name", node.Name)
+		add("Value", node.Value)
+	case *ast.ReturnStatement:
+		add("ReturnValue", node.ReturnValue)
+	case *ast.ExpressionStatement:
+		add("Expression", node.Expression)
+	case *ast.PrefixExpression:
+		add("Right", node.Right)
+	case *ast.InfixExpression:
+		add("Left", node.Left)
+		add("Right", node.Right)
+	case *ast.LogicalExpression:
+		add("Left", node.Left)
+		add("Right", node.Right)
+	case *ast.IfExpression:
+		add("Condition", node.Condition)
+		add("Consequence", node.Consequence)
+		add("Alternative", node.Alternative)
+	case *ast.FunctionLiteral:
+		params := make([]ast.Node, len(node.Parameters))
+		for i, p := range node.Parameters {
+			params[i] = p
+		}
+		addList("Parameters", params)
+		add("Body", node.Body)
+	case *ast.CallExpression:
+		add("Function", node.Function)
+		addList("Arguments", expressions(node.Arguments))
+	case *ast.ArrayLiteral:
+		addList("Elements", expressions(node.Elements))
+	case *ast.HashLiteral:
+		for i, pair := range node.Pairs {
+			add(fmt.Sprintf("Pairs[%d].Key", i), pair.Key)
+			add(fmt.Sprintf("Pairs[%d].Value", i), pair.Value)
+		}
+	case *ast.IndexExpression:
+		add("Left", node.Left)
+		add("Index", node.Index)
+	}
+
+	return out
+}
@@ -0,0 +1,139 @@
+package astdump
+
+// astdump は構文木を人が読みやすい形に書き出す
+
+import (
+	"bytes"
+	"monkey/ast"
+	"strconv"
+	"strings"
+)
+
+// 構文木をS式で書き出す
+// let x = 1 + 2 * 3; は (let x (+ 1 (* 2 3))) になる
+// Program は文ごとに1行ずつ並べる
+func Sexpr(node ast.Node) string {
+	var out bytes.Buffer
+	writeSexpr(&out, node)
+	return out.String()
+}
+
+func writeSexpr(out *bytes.Buffer, node ast.Node) {
+	// 構文エラーで値が入らなかった場所など
+	if ast.IsNil(node) {
+		out.WriteString("<nil>")
+		return
+	}
+
+	switch node := node.(type) {
+	case *ast.Program:
+		for i, s := range node.Statements {
+			if i > 0 {
+				out.WriteString("\n")
+			}
+			writeSexpr(out, s)
+		}
+
+	case *ast.LetStatement:
+		list(out, "let", node.Name, node.Value)
+
+	case *ast.ReturnStatement:
+		list(out, "return", node.ReturnValue)
+
+	case *ast.ExpressionStatement:
+		writeSexpr(out, node.Expression)
+
+	case *ast.BlockStatement:
+		nodes := make([]ast.Node, len(node.Statements))
+		for i, s := range node.Statements {
+			nodes[i] = s
+		}
+		list(out, "block", nodes...)
+
+	case *ast.Identifier:
+		out.WriteString(node.Value)
+
+	case *ast.IntegerLiteral:
+		out.WriteString(strconv.FormatInt(node.Value, 10))
+
+	case *ast.FloatLiteral:
+		out.WriteString(ast.FormatFloat(node.Value))
+
+	case *ast.StringLiteral:
+		out.WriteString(ast.Quote(node.Value))
+
+	case *ast.Boolean:
+		out.WriteString(strconv.FormatBool(node.Value))
+
+	case *ast.PrefixExpression:
+		list(out, node.Operator, node.Right)
+
+	case *ast.InfixExpression:
+		list(out, node.Operator, node.Left, node.Right)
+
+	case *ast.LogicalExpression:
+		list(out, node.Operator, node.Left, node.Right)
+
+	case *ast.IfExpression:
+		if node.Alternative == nil {
+			list(out, "if", node.Condition, node.Consequence)
+		} else {
+			list(out, "if", node.Condition, node.Consequence, node.Alternative)
+		}
+
+	case *ast.FunctionLiteral:
+		params := []string{}
+		for _, p := range node.Parameters {
+			params = append(params, p.Value)
+		}
+		out.WriteString("(fn (" + strings.Join(params, " ") + ") ")
+		writeSexpr(out, node.Body)
+		out.WriteString(")")
+
+	case *ast.CallExpression:
+		list(out, "call", append([]ast.Node{node.Function}, expressions(node.Arguments)...)...)
+
+	case *ast.ArrayLiteral:
+		list(out, "array", expressions(node.Elements)...)
+
+	case *ast.HashLiteral:
+		out.WriteString("(hash")
+		for _, pair := range node.Pairs {
+			out.WriteString(" ")
+			list(out, "", pair.Key, pair.Value)
+		}
+		out.WriteString(")")
+
+	case *ast.IndexExpression:
+		list(out, "index", node.Left, node.Index)
+
+	case *ast.BadStatement:
+		out.WriteString("(bad-statement)")
+
+	case *ast.BadExpression:
+		out.WriteString("(bad-expression)")
+
+	default:
+		out.WriteString("<unknown>")
+	}
+}
+
+// (head a b c) を書く。head が空なら (a b c)
+func list(out *bytes.Buffer, head string, nodes ...ast.Node) {
+	out.WriteString("(" + head)
+	for i, n := range nodes {
+		if head != "" || i > 0 {
+			out.WriteString(" ")
+		}
+		writeSexpr(out, n)
+	}
+	out.WriteString(")")
+}
+
+func expressions(exps []ast.Expression) []ast.Node {
+	nodes := make([]ast.Node, len(exps))
+	for i, e := range exps {
+		nodes[i] = e
+	}
+	return nodes
+}
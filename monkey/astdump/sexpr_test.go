@@ -0,0 +1,59 @@
+package astdump
+
+import (
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"testing"
+)
+
+func TestSexpr(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 1 + 2 * 3;", "(let x (+ 1 (* 2 3)))"},
+		{"return -a;", "(return (- a))"},
+		{"!true == false", "(== (! true) false)"},
+		{"a && b || c", "(|| (&& a b) c)"},
+		{"1.5; \"hi\\n\"", "1.5\n\"hi\\n\""},
+		{"if (x < y) { x } else { y; 1 }", "(if (< x y) (block x) (block y 1))"},
+		{"if (x) { }", "(if x (block))"},
+		{"fn(a, b) { return a + b; }", "(fn (a b) (block (return (+ a b))))"},
+		{"fn() { 1 }()", "(call (fn () (block 1)))"},
+		{"add(1, f(2))", "(call add 1 (call f 2))"},
+		{"[1, 2][0]", "(index (array 1 2) 0)"},
+		{"{\"a\": 1, true: [2]}", "(hash (\"a\" 1) (true (array 2)))"},
+		{"{}", "(hash)"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser errors for %q: %v", tt.input, p.Errors())
+		}
+
+		got := Sexpr(program)
+		if got != tt.expected {
+			t.Errorf("Sexpr(%q) wrong.\nexpected=%q\ngot=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestSexprWithErrors(t *testing.T) {
+	p := parser.New(lexer.New("let a = 1 @ 2; let b = ;"))
+	program := p.ParseProgram()
+
+	expected := "(let a 1)\n(bad-statement)\n(bad-statement)"
+	got := Sexpr(program)
+	if got != expected {
+		t.Errorf("Sexpr wrong.\nexpected=%q\ngot=%q", expected, got)
+	}
+
+	// 手で組み立てた途中の構文木でも落ちない
+	got = Sexpr(&ast.LetStatement{})
+	if got != "(let <nil> <nil>)" {
+		t.Errorf("Sexpr wrong.\nexpected=%q\ngot=%q", "(let <nil> <nil>)", got)
+	}
+}
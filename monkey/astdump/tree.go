@@ -0,0 +1,43 @@
+package astdump
+
+import (
+	"bytes"
+	"monkey/ast"
+)
+
+// 構文木を字下げした木の形で書き出す
+//
+//	Program
+//	└─ LetStatement
+//	   ├─ Name: Identifier x
+//	   └─ Value: InfixExpression +
+//	      ├─ Left: IntegerLiteral 1
+//	      └─ Right: IntegerLiteral 2
+func Tree(node ast.Node) string {
+	if ast.IsNil(node) {
+		return "<nil>\n"
+	}
+
+	var out bytes.Buffer
+	out.WriteString(label(node) + "\n")
+	writeTree(&out, node, "")
+	return out.String()
+}
+
+func writeTree(out *bytes.Buffer, node ast.Node, indent string) {
+	cs := children(node)
+	for i, c := range cs {
+		branch, next := "├─ ", "│  "
+		if i == len(cs)-1 {
+			branch, next = "└─ ", "   "
+		}
+
+		out.WriteString(indent + branch)
+		if c.name != "" {
+			out.WriteString(c.name + ": ")
+		}
+		out.WriteString(label(c.node) + "\n")
+
+		writeTree(out, c.node, indent+next)
+	}
+}
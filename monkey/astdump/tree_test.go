@@ -0,0 +1,98 @@
+package astdump
+
+import (
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"testing"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program
+}
+
+func TestTree(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"let x = 1 + 2 * 3;",
+			`Program
+└─ LetStatement
+   ├─ Name: Identifier x
+   └─ Value: InfixExpression +
+      ├─ Left: IntegerLiteral 1
+      └─ Right: InfixExpression *
+         ├─ Left: IntegerLiteral 2
+         └─ Right: IntegerLiteral 3
+`,
+		},
+		{
+			"if (a && !b) { f(1, \"s\") } else { [x][0] }; 2.5",
+			`Program
+├─ ExpressionStatement
+│  └─ Expression: IfExpression
+│     ├─ Condition: LogicalExpression &&
+│     │  ├─ Left: Identifier a
+│     │  └─ Right: PrefixExpression !
+│     │     └─ Right: Identifier b
+│     ├─ Consequence: BlockStatement
+│     │  └─ ExpressionStatement
+│     │     └─ Expression: CallExpression
+│     │        ├─ Function: Identifier f
+│     │        ├─ Arguments[0]: IntegerLiteral 1
+│     │        └─ Arguments[1]: StringLiteral "s"
+│     └─ Alternative: BlockStatement
+│        └─ ExpressionStatement
+│           └─ Expression: IndexExpression
+│              ├─ Left: ArrayLiteral
+│              │  └─ Elements[0]: Identifier x
+│              └─ Index: IntegerLiteral 0
+└─ ExpressionStatement
+   └─ Expression: FloatLiteral 2.5
+`,
+		},
+		{
+			"return fn(a) { {true: a} };",
+			`Program
+└─ ReturnStatement
+   └─ ReturnValue: FunctionLiteral
+      ├─ Parameters[0]: Identifier a
+      └─ Body: BlockStatement
+         └─ ExpressionStatement
+            └─ Expression: HashLiteral
+               ├─ Pairs[0].Key: Boolean true
+               └─ Pairs[0].Value: Identifier a
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		got := Tree(parse(t, tt.input))
+		if got != tt.expected {
+			t.Errorf("Tree(%q) wrong.\nexpected=\n%s\ngot=\n%s", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestTreeWithErrors(t *testing.T) {
+	program := parser.New(lexer.New("let a = ;")).ParseProgram()
+
+	expected := "Program\n└─ BadStatement\n"
+	if got := Tree(program); got != expected {
+		t.Errorf("Tree wrong.\nexpected=%q\ngot=%q", expected, got)
+	}
+
+	// 子ノードがない場所は飛ばす
+	expected = "LetStatement\n"
+	if got := Tree(&ast.LetStatement{}); got != expected {
+		t.Errorf("Tree wrong.\nexpected=%q\ngot=%q", expected, got)
+	}
+}
@@ -0,0 +1,97 @@
+package lexer
+
+import (
+	"monkey/token"
+)
+
+// コメントの扱い方
+type CommentMode int
+
+const (
+	SkipComments   CommentMode = iota // 読み飛ばす
+	EmitComments                      // token.COMMENT として返す
+	AttachComments                    // 次のトークンの Leading / 前のトークンの Trailing に付ける
+)
+
+// コメントの扱い方を変える
+// 何も指定しなければ SkipComments
+func WithComments(mode CommentMode) Option {
+	return func(l *Lexer) {
+		l.comments = mode
+	}
+}
+
+// // か /* で始まっているか
+func (l *Lexer) atComment() bool {
+	return l.ch == '/' && (l.peakChar() == '/' || l.peakChar() == '*')
+}
+
+// コメントを1つ読む
+// 呼ばれた時点で l.ch は最初の / 、終わった時点でコメントの直後になっている
+func (l *Lexer) readComment() token.Comment {
+	start := l.pos()
+	l.startRecording()
+
+	if l.peakChar() == '/' {
+		// 行末まで (改行は含めない)
+		for l.ch != '\n' && l.ch != 0 {
+			l.readChar()
+		}
+	} else {
+		l.readBlockComment(start)
+	}
+
+	return token.Comment{
+		Text: l.stopRecording(),
+		Pos:  start,
+		End:  l.pos(),
+	}
+}
+
+// /* */ は入れ子にできる
+// /* a /* b */ c */ は全体で1つのコメント
+func (l *Lexer) readBlockComment(start token.Position) {
+	depth := 0
+
+	for {
+		switch {
+		case l.ch == 0:
+			l.error(start, l.pos(), "comment not terminated")
+			return
+		case l.ch == '/' && l.peakChar() == '*':
+			depth += 1
+			l.readChar()
+		case l.ch == '*' && l.peakChar() == '/':
+			depth -= 1
+			l.readChar()
+			if depth == 0 {
+				l.readChar()
+				return
+			}
+		}
+		l.readChar()
+	}
+}
+
+// トークンと同じ行に続くコメントを読む
+// 改行を越えた先のコメントは次のトークンの Leading になる
+func (l *Lexer) readTrailingComments() []token.Comment {
+	var trailing []token.Comment
+
+	for {
+		for l.ch == ' ' || l.ch == '\t' || l.ch == '\r' {
+			l.readChar()
+		}
+		if !l.atComment() {
+			return trailing
+		}
+
+		comment := l.readComment()
+		trailing = append(trailing, comment)
+
+		// // は行末まで続くのでこれで終わり
+		if comment.Text[1] == '/' {
+			return trailing
+		}
+	}
+}
@@ -1,43 +1,219 @@
 package lexer
 
 import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
 	"monkey/token"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 // 字句解析器
+// 入力は少しずつ読むので、全体をメモリに載せる必要はない
 type Lexer struct {
-	input        string
-	position     int  // 現在の文字chの位置
-	readPosition int  // これから読み込む文字の位置
-	ch           byte // 現在検査中の文字
+	r            *bufio.Reader
+	eof          bool   // 入力を最後まで読んだら true
+	filename     string // 位置情報に載せるファイル名
+	position     int    // 現在の文字chの位置 (バイト単位)
+	readPosition int    // これから読み込む文字の位置 (バイト単位)
+	ch           rune   // 現在検査中の文字
+	chBytes      []byte // chの元のバイト列
+	invalid      bool   // chがUTF-8として正しくないバイトのとき true
+	line         int    // 現在の文字chの行番号 (1始まり)
+	column       int    // 現在の文字chの列番号 (1始まり、文字単位)
+
+	comments CommentMode // コメントの扱い
+
+	// 識別子などの元の文字列を取り出すために、読み進めた文字を記録しておく
+	recording bool
+	recorded  bytes.Buffer
+
+	errors []*Error // 見つかったエラー
+}
+
+// 字句解析中に見つかったエラー
+// 閉じていない文字列など、トークンに区切れない入力を報告する
+type Error struct {
+	Pos token.Position
+	End token.Position
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
+
+// これまでに見つかったエラーを見つかった順に返す
+func (l *Lexer) Errors() []*Error {
+	return l.errors
+}
+
+func (l *Lexer) error(pos, end token.Position, format string, a ...interface{}) {
+	l.errors = append(l.errors, &Error{Pos: pos, End: end, Msg: fmt.Sprintf(format, a...)})
+}
+
+// New に渡す設定
+type Option func(*Lexer)
+
+// 位置情報にファイル名を載せる
+func WithFilename(filename string) Option {
+	return func(l *Lexer) {
+		l.filename = filename
+	}
 }
 
 func (l *Lexer) readChar() {
 	// ポインタレシーバを使うことでlの中身を変更することができる
 	// 普通のレシーバだとlのコピーを触ることになるので変更が反映されない
-	if l.readPosition >= len(l.input) {
-		l.ch = 0 // ASCIIコードのNULLに対応
-	} else {
-		l.ch = l.input[l.readPosition]
+
+	// すでに終わりまで読んでいたら何もしない
+	if l.eof {
+		return
 	}
+
+	// 改行を読み終えたら次の行へ
+	if l.ch == '\n' {
+		l.line += 1
+		l.column = 0
+	}
+	l.column += 1
+
+	if l.recording {
+		l.recorded.Write(l.chBytes)
+	}
+
 	l.position = l.readPosition
-	l.readPosition += 1
+	l.invalid = false
+
+	ch, b := l.peekRune()
+	if b == nil {
+		l.ch = 0 // ASCIIコードのNULLに対応
+		l.chBytes = nil
+		l.eof = true
+		return
+	}
+
+	// 1文字が何バイトかは文字によって違う
+	// b は次に読み込むまでしか使えないのでコピーしておく
+	l.chBytes = append(l.chBytes[:0], b...)
+	l.r.Discard(len(b))
+	l.ch = ch
+	l.readPosition += len(b)
+
+	// 正しくないバイトは1バイトずつ RuneError として読む
+	if ch == utf8.RuneError && len(b) == 1 {
+		l.invalid = true
+		l.error(l.pos(), l.nextPos(), "invalid UTF-8 encoding (byte 0x%02x)", b[0])
+	}
 }
 
-func (l *Lexer) peakChar() byte {
-	if l.readPosition >= len(l.input) {
-		return 0 // ASCIIコードのNULLに対応
-	} else {
-		return l.input[l.readPosition]
+// まだ読み込んでいない次の1文字を、読み進めずに覗く
+// 入力の終わりなら b は nil
+func (l *Lexer) peekRune() (ch rune, b []byte) {
+	b, err := l.r.Peek(1)
+	if len(b) == 0 {
+		if err != nil && err != io.EOF {
+			l.error(l.pos(), l.pos(), "read error: %s", err)
+		}
+		return 0, nil
 	}
+
+	// 先頭のバイトで何バイトの文字かが分かる
+	// 必要な分だけ覗くことで、入力が届くのを余計に待たない
+	b, _ = l.r.Peek(utf8Len(b[0]))
+	ch, width := utf8.DecodeRune(b)
+	return ch, b[:width]
+}
+
+func utf8Len(b byte) int {
+	switch {
+	case b < 0xC0:
+		return 1
+	case b < 0xE0:
+		return 2
+	case b < 0xF0:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// ここから読み進めた文字を記録し始める
+func (l *Lexer) startRecording() {
+	l.recorded.Reset()
+	l.recording = true
+}
+
+// startRecording から今の文字chの手前までの元の文字列を返す
+func (l *Lexer) stopRecording() string {
+	l.recording = false
+	return l.recorded.String()
+}
+
+// 現在の文字chの位置
+func (l *Lexer) pos() token.Position {
+	return token.Position{
+		Filename: l.filename,
+		Offset:   l.position,
+		Line:     l.line,
+		Column:   l.column,
+	}
+}
+
+// 現在の文字chの直後の位置
+func (l *Lexer) nextPos() token.Position {
+	pos := l.pos()
+	pos.Offset = l.readPosition
+	pos.Column += 1
+	return pos
+}
+
+func (l *Lexer) peakChar() rune {
+	ch, _ := l.peekRune() // 終わりなら 0 (ASCIIコードのNULLに対応)
+	return ch
 }
 
 func (l *Lexer) NextToken() token.Token {
 	// 文字列を読み込んでTokenにして返す
 
+	var leading []token.Comment
+
+	// 先にコメントを片付ける
+	for {
+		l.skipWhitespace()
+		if !l.atComment() {
+			break
+		}
+
+		comment := l.readComment()
+		switch l.comments {
+		case EmitComments:
+			return token.Token{Type: token.COMMENT, Literal: comment.Text, Pos: comment.Pos, End: comment.End}
+		case AttachComments:
+			leading = append(leading, comment)
+		}
+	}
+
+	tok := l.nextToken()
+
+	if l.comments == AttachComments {
+		tok.Leading = leading
+		if tok.Type != token.EOF {
+			tok.Trailing = l.readTrailingComments()
+		}
+	}
+
+	return tok
+}
+
+// コメントと空白を読み飛ばした後の、次のトークンを読む
+func (l *Lexer) nextToken() token.Token {
 	var tok token.Token
 
-	l.skipWhitespace()
+	start := l.pos() // トークンの開始位置
 
 	switch l.ch {
 	case '=':
@@ -64,14 +240,48 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.ASTERISK, l.ch)
 	case '/':
 		tok = newToken(token.SLASH, l.ch)
+	case '%':
+		tok = newToken(token.PERCENT, l.ch)
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		// `<=` か `<` か
+		if l.peakChar() == '=' {
+			l.readChar()
+			tok = newTokenFromString(token.LT_EQ, "<=")
+		} else {
+			tok = newToken(token.LT, l.ch)
+		}
 	case '>':
-		tok = newToken(token.GT, l.ch)
+		// `>=` か `>` か
+		if l.peakChar() == '=' {
+			l.readChar()
+			tok = newTokenFromString(token.GT_EQ, ">=")
+		} else {
+			tok = newToken(token.GT, l.ch)
+		}
+	case '&':
+		// `&` だけの演算子は無い
+		if l.peakChar() == '&' {
+			l.readChar()
+			tok = newTokenFromString(token.AND, "&&")
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+			l.error(start, l.nextPos(), "illegal character %q (did you mean \"&&\"?)", l.ch)
+		}
+	case '|':
+		// `|` だけの演算子は無い
+		if l.peakChar() == '|' {
+			l.readChar()
+			tok = newTokenFromString(token.OR, "||")
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+			l.error(start, l.nextPos(), "illegal character %q (did you mean \"||\"?)", l.ch)
+		}
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
+	case ':':
+		tok = newToken(token.COLON, l.ch)
 	case '(':
 		tok = newToken(token.LPAREN, l.ch)
 	case ')':
@@ -80,43 +290,187 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
 		tok = newToken(token.RBRACE, l.ch)
+	case '[':
+		tok = newToken(token.LBRACKET, l.ch)
+	case ']':
+		tok = newToken(token.RBRACKET, l.ch)
+	case '"':
+		tok.Type = token.STRING
+		tok.Literal = l.readString()
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
+		tok.Pos = start
+		tok.End = start
+		return tok
 	default:
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()          // 文字列の塊を取得
 			tok.Type = token.LookupIdent(tok.Literal) // keywords（予約語かを判定）
+			tok.Pos = start
+			tok.End = l.pos()
 			return tok
 			// ここは1文字進める必要がないための措置
 			// readIdentifierの最後でreadChar()しているからだけどあんまりよくない気がする
 		} else if isDigit(l.ch) {
-			tok.Literal = l.readNumber()
-			tok.Type = token.INT
+			tok.Literal, tok.Type = l.readNumber()
+			tok.Pos = start
+			tok.End = l.pos()
 			return tok
+		} else if l.invalid {
+			// エラーは readChar で報告済み
+			tok = newTokenFromString(token.ILLEGAL, string(l.chBytes))
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
+			l.error(start, l.nextPos(), "illegal character %q", l.ch)
 		}
 	}
 
 	l.readChar() // 1文字すすめる
+	tok.Pos = start
+	tok.End = l.pos()
 	return tok
 }
 
+// "..." の中身を読んでエスケープを解釈した文字列を返す
+// 呼ばれた時点で l.ch は開きの " 、終わった時点で閉じの " になっている
+// 閉じないまま行末かファイルの終わりに来たらエラーにする
+func (l *Lexer) readString() string {
+	start := l.pos()
+	var out strings.Builder
+
+	for {
+		l.readChar()
+
+		switch l.ch {
+		case '"':
+			return out.String()
+		case 0, '\n':
+			l.error(start, l.pos(), "string literal not terminated")
+			return out.String()
+		case '\\':
+			// \ のすぐ後で終わっている場合は、次の周で閉じていないエラーになる
+			if next := l.peakChar(); next == 0 || next == '\n' {
+				continue
+			}
+			l.readEscape(&out)
+		default:
+			out.WriteRune(l.ch)
+		}
+	}
+}
+
+// \ の後ろを読んで対応する文字を out に書く
+// 呼ばれた時点で l.ch は \ 、終わった時点でエスケープの最後の文字になっている
+func (l *Lexer) readEscape(out *strings.Builder) {
+	start := l.pos()
+	l.readChar()
+
+	switch l.ch {
+	case 'n':
+		out.WriteByte('\n')
+	case 't':
+		out.WriteByte('\t')
+	case 'r':
+		out.WriteByte('\r')
+	case '"':
+		out.WriteByte('"')
+	case '\\':
+		out.WriteByte('\\')
+	case 'u':
+		l.readUnicodeEscape(start, out)
+	default:
+		l.error(start, l.nextPos(), "unknown escape sequence \\%c", l.ch)
+		out.WriteRune(l.ch)
+	}
+}
+
+// \u{1F600} のように { } の中に16進数で書かれたコードポイントを読む
+func (l *Lexer) readUnicodeEscape(start token.Position, out *strings.Builder) {
+	if l.peakChar() != '{' {
+		l.error(start, l.nextPos(), "invalid unicode escape: missing '{'")
+		return
+	}
+	l.readChar()
+
+	var value rune
+	digits := 0
+	for isHexDigit(l.peakChar()) {
+		l.readChar()
+		value = value*16 + hexValue(l.ch)
+		digits += 1
+		if digits > 6 {
+			break
+		}
+	}
+
+	if l.peakChar() != '}' || digits == 0 || digits > 6 {
+		l.error(start, l.nextPos(), "invalid unicode escape: expected 1 to 6 hex digits in braces")
+		return
+	}
+	l.readChar()
+
+	// サロゲートと範囲外はUTF-8で表せない
+	if value > 0x10FFFF || (0xD800 <= value && value <= 0xDFFF) {
+		l.error(start, l.nextPos(), "invalid unicode escape: U+%X is not a valid code point", value)
+		return
+	}
+	out.WriteRune(value)
+}
+
 func (l *Lexer) readIdentifier() string {
-	position := l.position
-	for isLetter(l.ch) { // l.chはループのたびに値が変わり、再評価される
+	l.startRecording()
+	// 2文字目からは数字も使える
+	for isLetter(l.ch) || unicode.IsDigit(l.ch) { // l.chはループのたびに値が変わり、再評価される
 		l.readChar() // readCharを使うことで終わりになるまで次々と文字を読んでいく
 	}
-	return l.input[position:l.position]
+	return l.stopRecording()
+}
+
+// 数値リテラルを読んで、token.INT か token.FLOAT かと一緒に返す
+// 0x1F 0o17 0b1010 1_000_000 3.14 1e-9 の形に対応する
+// 桁の並びが正しいか (0b12 など) や範囲はパーサが strconv で確かめる
+func (l *Lexer) readNumber() (string, token.TokenType) {
+	l.startRecording()
+
+	// 0x 0o 0b で始まるものは整数だけ
+	if l.ch == '0' && isBasePrefix(l.peakChar()) {
+		l.readChar()
+		l.readChar()
+		for isHexDigit(l.ch) || l.ch == '_' {
+			l.readChar()
+		}
+		return l.stopRecording(), token.INT
+	}
+
+	tokenType := token.TokenType(token.INT)
+	l.readDigits()
+
+	// . の後ろに数字が続くときだけ小数
+	if l.ch == '.' && isDigit(l.peakChar()) {
+		tokenType = token.FLOAT
+		l.readChar()
+		l.readDigits()
+	}
+
+	// 指数
+	if l.ch == 'e' || l.ch == 'E' {
+		tokenType = token.FLOAT
+		l.readChar()
+		if l.ch == '+' || l.ch == '-' {
+			l.readChar()
+		}
+		l.readDigits()
+	}
+
+	return l.stopRecording(), tokenType
 }
 
-func (l *Lexer) readNumber() string {
-	position := l.position
-	for isDigit(l.ch) { // l.chはループのたびに値が変わり、再評価される
+// 数字と区切りの _ を読む
+func (l *Lexer) readDigits() {
+	for isDigit(l.ch) || l.ch == '_' { // l.chはループのたびに値が変わり、再評価される
 		l.readChar() // readCharを使うことで終わりになるまで次々と文字を読んでいく
 	}
-	return l.input[position:l.position]
 }
 
 func (l *Lexer) skipWhitespace() {
@@ -125,14 +479,35 @@ func (l *Lexer) skipWhitespace() {
 	}
 }
 
-func New(input string) *Lexer {
-	l := &Lexer{input: input}
+func New(input string, opts ...Option) *Lexer {
+	return NewReader(strings.NewReader(input), opts...)
+}
+
+// io.Reader から少しずつ読みながら字句解析する
+// トークンと位置情報は New に同じ内容を文字列で渡したときと変わらない
+func NewReader(r io.Reader, opts ...Option) *Lexer {
+	l := &Lexer{r: bufio.NewReader(r), line: 1}
 	// &でポインタを返すようにする
+	for _, opt := range opts {
+		opt(l)
+	}
 	l.readChar()
+	l.skipShebang()
 	return l
 }
 
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+// スクリプトとして直接実行できるように、1行目の #! は読み飛ばす
+// 改行は残すので2行目以降の位置はそのまま
+func (l *Lexer) skipShebang() {
+	if l.ch != '#' || l.peakChar() != '!' {
+		return
+	}
+	for l.ch != '\n' && l.ch != 0 {
+		l.readChar()
+	}
+}
+
+func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
 
@@ -140,10 +515,36 @@ func newTokenFromString(tokenType token.TokenType, str string) token.Token {
 	return token.Token{Type: tokenType, Literal: str}
 }
 
-func isLetter(ch byte) bool {
-	return ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z') || ch == '_'
+// 変数名に使える文字
+// 日本語などASCII以外の文字も使える
+func isLetter(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_'
+}
+
+func isBasePrefix(ch rune) bool {
+	switch ch {
+	case 'x', 'X', 'o', 'O', 'b', 'B':
+		return true
+	}
+	return false
+}
+
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || ('a' <= ch && ch <= 'f') || ('A' <= ch && ch <= 'F')
+}
+
+func hexValue(ch rune) rune {
+	switch {
+	case '0' <= ch && ch <= '9':
+		return ch - '0'
+	case 'a' <= ch && ch <= 'f':
+		return ch - 'a' + 10
+	default:
+		return ch - 'A' + 10
+	}
 }
 
-func isDigit(ch byte) bool {
+// 数値リテラルに使えるのはASCIIの数字だけ
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
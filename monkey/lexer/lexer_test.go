@@ -3,8 +3,11 @@ package lexer
 // lexerは字句解析器: ソースコードをトークン列に変換する
 
 import (
+	"fmt"
 	"monkey/token"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestNextToken(t *testing.T) {
@@ -18,7 +21,7 @@ func TestNextToken(t *testing.T) {
 
 	let result = add(five, ten);
 
-	!-/*5;
+	!-/ *5;
 	5    <10  >5;
 
 	if (5<10){
@@ -135,3 +138,647 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 10;\n  x == 5\n"
+
+	tests := []struct {
+		expectedType token.TokenType
+		expectedPos  token.Position
+		expectedEnd  token.Position
+	}{
+		{token.LET, token.Position{Filename: "a.mk", Offset: 0, Line: 1, Column: 1}, token.Position{Filename: "a.mk", Offset: 3, Line: 1, Column: 4}},
+		{token.IDENT, token.Position{Filename: "a.mk", Offset: 4, Line: 1, Column: 5}, token.Position{Filename: "a.mk", Offset: 5, Line: 1, Column: 6}},
+		{token.ASSIGN, token.Position{Filename: "a.mk", Offset: 6, Line: 1, Column: 7}, token.Position{Filename: "a.mk", Offset: 7, Line: 1, Column: 8}},
+		{token.INT, token.Position{Filename: "a.mk", Offset: 8, Line: 1, Column: 9}, token.Position{Filename: "a.mk", Offset: 10, Line: 1, Column: 11}},
+		{token.SEMICOLON, token.Position{Filename: "a.mk", Offset: 10, Line: 1, Column: 11}, token.Position{Filename: "a.mk", Offset: 11, Line: 1, Column: 12}},
+		{token.IDENT, token.Position{Filename: "a.mk", Offset: 14, Line: 2, Column: 3}, token.Position{Filename: "a.mk", Offset: 15, Line: 2, Column: 4}},
+		{token.EQ, token.Position{Filename: "a.mk", Offset: 16, Line: 2, Column: 5}, token.Position{Filename: "a.mk", Offset: 18, Line: 2, Column: 7}},
+		{token.INT, token.Position{Filename: "a.mk", Offset: 19, Line: 2, Column: 8}, token.Position{Filename: "a.mk", Offset: 20, Line: 2, Column: 9}},
+		{token.EOF, token.Position{Filename: "a.mk", Offset: 21, Line: 3, Column: 1}, token.Position{Filename: "a.mk", Offset: 21, Line: 3, Column: 1}},
+	}
+
+	l := New(input, WithFilename("a.mk"))
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Pos != tt.expectedPos {
+			t.Fatalf("tests[%d] - pos wrong. expected=%+v, got=%+v",
+				i, tt.expectedPos, tok.Pos)
+		}
+
+		if tok.End != tt.expectedEnd {
+			t.Fatalf("tests[%d] - end wrong. expected=%+v, got=%+v",
+				i, tt.expectedEnd, tok.End)
+		}
+	}
+}
+
+func TestStringLiterals(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedLiteral string
+	}{
+		{`"foobar"`, "foobar"},
+		{`"foo bar"`, "foo bar"},
+		{`""`, ""},
+		{`"a\nb\tc"`, "a\nb\tc"},
+		{`"say \"hi\""`, `say "hi"`},
+		{`"back\\slash"`, `back\slash`},
+		{`"\u{41}\u{3042}\u{1F600}"`, "Aあ😀"},
+		{`"日本語"`, "日本語"},
+	}
+
+	for i, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+
+		if tok.Type != token.STRING {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, token.STRING, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.End.Offset != len(tt.input) {
+			t.Fatalf("tests[%d] - end wrong. expected=%d, got=%d", i, len(tt.input), tok.End.Offset)
+		}
+		if len(l.Errors()) != 0 {
+			t.Fatalf("tests[%d] - unexpected errors: %v", i, l.Errors())
+		}
+		if next := l.NextToken(); next.Type != token.EOF {
+			t.Fatalf("tests[%d] - expected EOF, got=%q", i, next.Type)
+		}
+	}
+}
+
+func TestStringLiteralErrors(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedPos string
+		expectedMsg string
+	}{
+		{`let s = "abc`, "1:9", "string literal not terminated"},
+		{"\"abc\nlet", "1:1", "string literal not terminated"},
+		{`"abc\`, "1:1", "string literal not terminated"},
+		{`"a\qb"`, "1:3", `unknown escape sequence \q`},
+		{`"\u41"`, "1:2", "invalid unicode escape: missing '{'"},
+		{`"\u{}"`, "1:2", "invalid unicode escape: expected 1 to 6 hex digits in braces"},
+		{`"\u{D800}"`, "1:2", "invalid unicode escape: U+D800 is not a valid code point"},
+		{"1 @ 2", "1:3", "illegal character '@'"},
+	}
+
+	for i, tt := range tests {
+		l := New(tt.input)
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		}
+
+		errors := l.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("tests[%d] - expected 1 error, got %d: %v", i, len(errors), errors)
+		}
+		if errors[0].Pos.String() != tt.expectedPos {
+			t.Errorf("tests[%d] - pos wrong. expected=%s, got=%s", i, tt.expectedPos, errors[0].Pos)
+		}
+		if errors[0].Msg != tt.expectedMsg {
+			t.Errorf("tests[%d] - message wrong. expected=%q, got=%q", i, tt.expectedMsg, errors[0].Msg)
+		}
+	}
+}
+
+func TestCollectionTokens(t *testing.T) {
+	input := `[1, 2]; {"foo": "bar"}`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LBRACKET, "["},
+		{token.INT, "1"},
+		{token.COMMA, ","},
+		{token.INT, "2"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
+		{token.LBRACE, "{"},
+		{token.STRING, "foo"},
+		{token.COLON, ":"},
+		{token.STRING, "bar"},
+		{token.RBRACE, "}"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestSkipComments(t *testing.T) {
+	input := `// 先頭のコメント
+	let x = 10 / 2; // 割り算
+	/* ブロック /* 入れ子 */ もOK */ x`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "10"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Leading != nil || tok.Trailing != nil {
+			t.Fatalf("tests[%d] - comments attached in SkipComments mode", i)
+		}
+	}
+}
+
+func TestEmitComments(t *testing.T) {
+	input := "a // one\n/* two /* three */ */ b"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedPos     string
+	}{
+		{token.IDENT, "a", "1:1"},
+		{token.COMMENT, "// one", "1:3"},
+		{token.COMMENT, "/* two /* three */ */", "2:1"},
+		{token.IDENT, "b", "2:23"},
+		{token.EOF, "", "2:24"},
+	}
+
+	l := New(input, WithComments(EmitComments))
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Pos.String() != tt.expectedPos {
+			t.Fatalf("tests[%d] - pos wrong. expected=%s, got=%s", i, tt.expectedPos, tok.Pos)
+		}
+	}
+}
+
+func TestAttachComments(t *testing.T) {
+	input := `// doc for x
+	let x = 1; // trailing
+	/* a */ /* b */
+	x /* c */ // d
+	// end of file`
+
+	l := New(input, WithComments(AttachComments))
+
+	var toks []token.Token
+	for tok := l.NextToken(); ; tok = l.NextToken() {
+		toks = append(toks, tok)
+		if tok.Type == token.EOF {
+			break
+		}
+	}
+
+	texts := func(comments []token.Comment) []string {
+		result := []string{}
+		for _, c := range comments {
+			result = append(result, c.Text)
+		}
+		return result
+	}
+
+	tests := []struct {
+		index            int
+		expectedType     token.TokenType
+		expectedLeading  []string
+		expectedTrailing []string
+	}{
+		{0, token.LET, []string{"// doc for x"}, []string{}},
+		{4, token.SEMICOLON, []string{}, []string{"// trailing"}},
+		{5, token.IDENT, []string{"/* a */", "/* b */"}, []string{"/* c */", "// d"}},
+		{6, token.EOF, []string{"// end of file"}, []string{}},
+	}
+
+	for _, tt := range tests {
+		tok := toks[tt.index]
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("toks[%d] - tokentype wrong. expected=%q, got=%q", tt.index, tt.expectedType, tok.Type)
+		}
+		if fmt.Sprint(texts(tok.Leading)) != fmt.Sprint(tt.expectedLeading) {
+			t.Errorf("toks[%d] - leading wrong. expected=%q, got=%q", tt.index, tt.expectedLeading, texts(tok.Leading))
+		}
+		if fmt.Sprint(texts(tok.Trailing)) != fmt.Sprint(tt.expectedTrailing) {
+			t.Errorf("toks[%d] - trailing wrong. expected=%q, got=%q", tt.index, tt.expectedTrailing, texts(tok.Trailing))
+		}
+	}
+}
+
+func TestUnterminatedComment(t *testing.T) {
+	l := New("1 /* a /* b */")
+
+	if tok := l.NextToken(); tok.Type != token.INT {
+		t.Fatalf("expected INT, got=%q", tok.Type)
+	}
+	if tok := l.NextToken(); tok.Type != token.EOF {
+		t.Fatalf("expected EOF, got=%q", tok.Type)
+	}
+
+	errors := l.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("expected 1 error, got %d: %v", len(errors), errors)
+	}
+	if errors[0].Pos.String() != "1:3" || errors[0].Msg != "comment not terminated" {
+		t.Errorf("error wrong. got=%s", errors[0])
+	}
+}
+
+func TestUnicodeIdentifiers(t *testing.T) {
+	input := "let 変数 = café + x1;\n\"日本\" ü_2"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedPos     token.Position
+	}{
+		{token.LET, "let", token.Position{Offset: 0, Line: 1, Column: 1}},
+		{token.IDENT, "変数", token.Position{Offset: 4, Line: 1, Column: 5}},
+		{token.ASSIGN, "=", token.Position{Offset: 11, Line: 1, Column: 8}},
+		{token.IDENT, "café", token.Position{Offset: 13, Line: 1, Column: 10}},
+		{token.PLUS, "+", token.Position{Offset: 19, Line: 1, Column: 15}},
+		{token.IDENT, "x1", token.Position{Offset: 21, Line: 1, Column: 17}},
+		{token.SEMICOLON, ";", token.Position{Offset: 23, Line: 1, Column: 19}},
+		{token.STRING, "日本", token.Position{Offset: 25, Line: 2, Column: 1}},
+		{token.IDENT, "ü_2", token.Position{Offset: 34, Line: 2, Column: 6}},
+		{token.EOF, "", token.Position{Offset: 38, Line: 2, Column: 9}},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Pos != tt.expectedPos {
+			t.Fatalf("tests[%d] - pos wrong. expected=%+v, got=%+v", i, tt.expectedPos, tok.Pos)
+		}
+	}
+
+	if len(l.Errors()) != 0 {
+		t.Fatalf("unexpected errors: %v", l.Errors())
+	}
+}
+
+func TestInvalidUTF8(t *testing.T) {
+	input := "let a\xff = \"b\xfe\";"
+
+	l := New(input)
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "a"},
+		{token.ILLEGAL, "\xff"},
+		{token.ASSIGN, "="},
+		{token.STRING, "b�"},
+		{token.SEMICOLON, ";"},
+		{token.EOF, ""},
+	}
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+
+	expected := []string{
+		"1:6: invalid UTF-8 encoding (byte 0xff)",
+		"1:12: invalid UTF-8 encoding (byte 0xfe)",
+	}
+
+	errors := l.Errors()
+	if len(errors) != len(expected) {
+		t.Fatalf("expected %d errors, got %d: %v", len(expected), len(errors), errors)
+	}
+	for i, e := range errors {
+		if e.Error() != expected[i] {
+			t.Errorf("errors[%d] wrong. expected=%q, got=%q", i, expected[i], e.Error())
+		}
+	}
+}
+
+func TestNumberLiterals(t *testing.T) {
+	input := "0x1F 0o17 0b1010 1_000_000 3.14 1e-9 2.5E+3 7 0 1.foo 0XAB_CD"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INT, "0x1F"},
+		{token.INT, "0o17"},
+		{token.INT, "0b1010"},
+		{token.INT, "1_000_000"},
+		{token.FLOAT, "3.14"},
+		{token.FLOAT, "1e-9"},
+		{token.FLOAT, "2.5E+3"},
+		{token.INT, "7"},
+		{token.INT, "0"},
+		{token.INT, "1"}, // . の後ろが数字でなければ小数にしない
+		{token.ILLEGAL, "."},
+		{token.IDENT, "foo"},
+		{token.INT, "0XAB_CD"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestTwoCharacterOperators(t *testing.T) {
+	input := "a <= b >= c && d || e % f < g > h & |"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.LT_EQ, "<="},
+		{token.IDENT, "b"},
+		{token.GT_EQ, ">="},
+		{token.IDENT, "c"},
+		{token.AND, "&&"},
+		{token.IDENT, "d"},
+		{token.OR, "||"},
+		{token.IDENT, "e"},
+		{token.PERCENT, "%"},
+		{token.IDENT, "f"},
+		{token.LT, "<"},
+		{token.IDENT, "g"},
+		{token.GT, ">"},
+		{token.IDENT, "h"},
+		{token.ILLEGAL, "&"},
+		{token.ILLEGAL, "|"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+
+	if len(l.Errors()) != 2 {
+		t.Fatalf("expected 2 errors, got %d: %v", len(l.Errors()), l.Errors())
+	}
+}
+
+// New と NewReader が同じトークンと位置を返すことを、同じ表で確かめる
+func TestReaderConstructors(t *testing.T) {
+	constructors := []struct {
+		name string
+		new  func(input string) *Lexer
+	}{
+		{"New", func(input string) *Lexer { return New(input) }},
+		{"NewReader", func(input string) *Lexer { return NewReader(strings.NewReader(input)) }},
+		// 1バイトずつしか返さない Reader でも、文字の途中で切れずに読めること
+		{"NewReader/OneByte", func(input string) *Lexer {
+			return NewReader(iotest.OneByteReader(strings.NewReader(input)))
+		}},
+	}
+
+	pos := func(offset, line, column int) token.Position {
+		return token.Position{Offset: offset, Line: line, Column: column}
+	}
+
+	tests := []struct {
+		input          string
+		expected       []token.Token
+		expectedErrors int
+	}{
+		{
+			"let x = 10;\n  x >= 5",
+			[]token.Token{
+				{Type: token.LET, Literal: "let", Pos: pos(0, 1, 1), End: pos(3, 1, 4)},
+				{Type: token.IDENT, Literal: "x", Pos: pos(4, 1, 5), End: pos(5, 1, 6)},
+				{Type: token.ASSIGN, Literal: "=", Pos: pos(6, 1, 7), End: pos(7, 1, 8)},
+				{Type: token.INT, Literal: "10", Pos: pos(8, 1, 9), End: pos(10, 1, 11)},
+				{Type: token.SEMICOLON, Literal: ";", Pos: pos(10, 1, 11), End: pos(11, 1, 12)},
+				{Type: token.IDENT, Literal: "x", Pos: pos(14, 2, 3), End: pos(15, 2, 4)},
+				{Type: token.GT_EQ, Literal: ">=", Pos: pos(16, 2, 5), End: pos(18, 2, 7)},
+				{Type: token.INT, Literal: "5", Pos: pos(19, 2, 8), End: pos(20, 2, 9)},
+				{Type: token.EOF, Literal: "", Pos: pos(20, 2, 9), End: pos(20, 2, 9)},
+			},
+			0,
+		},
+		{
+			"名前 = \"é\\u{1F600}\" // コメント\n3.14e2",
+			[]token.Token{
+				{Type: token.IDENT, Literal: "名前", Pos: pos(0, 1, 1), End: pos(6, 1, 3)},
+				{Type: token.ASSIGN, Literal: "=", Pos: pos(7, 1, 4), End: pos(8, 1, 5)},
+				{Type: token.STRING, Literal: "é😀", Pos: pos(9, 1, 6), End: pos(22, 1, 18)},
+				{Type: token.FLOAT, Literal: "3.14e2", Pos: pos(39, 2, 1), End: pos(45, 2, 7)},
+				{Type: token.EOF, Literal: "", Pos: pos(45, 2, 7), End: pos(45, 2, 7)},
+			},
+			0,
+		},
+		{
+			"a \xff 0x1F /* 閉じない",
+			[]token.Token{
+				{Type: token.IDENT, Literal: "a", Pos: pos(0, 1, 1), End: pos(1, 1, 2)},
+				{Type: token.ILLEGAL, Literal: "\xff", Pos: pos(2, 1, 3), End: pos(3, 1, 4)},
+				{Type: token.INT, Literal: "0x1F", Pos: pos(4, 1, 5), End: pos(8, 1, 9)},
+				{Type: token.EOF, Literal: "", Pos: pos(24, 1, 17), End: pos(24, 1, 17)},
+			},
+			2,
+		},
+	}
+
+	for _, c := range constructors {
+		for i, tt := range tests {
+			l := c.new(tt.input)
+
+			for j, expected := range tt.expected {
+				tok := l.NextToken()
+
+				if tok.Type != expected.Type || tok.Literal != expected.Literal {
+					t.Fatalf("%s: tests[%d][%d] - token wrong. expected=%s %q, got=%s %q",
+						c.name, i, j, expected.Type, expected.Literal, tok.Type, tok.Literal)
+				}
+
+				if tok.Pos != expected.Pos || tok.End != expected.End {
+					t.Fatalf("%s: tests[%d][%d] - range wrong. expected=%s-%s, got=%s-%s",
+						c.name, i, j, expected.Pos, expected.End, tok.Pos, tok.End)
+				}
+			}
+
+			if len(l.Errors()) != tt.expectedErrors {
+				t.Errorf("%s: tests[%d] - wrong number of errors. expected=%d, got=%d (%v)",
+					c.name, i, tt.expectedErrors, len(l.Errors()), l.Errors())
+			}
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tokens, errs := Tokenize("let x = 1 & 2;")
+
+	expected := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "1"},
+		{token.ILLEGAL, "&"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+	}
+
+	if len(tokens) != len(expected) {
+		t.Fatalf("wrong number of tokens. expected=%d, got=%d (%v)", len(expected), len(tokens), tokens)
+	}
+
+	for i, tt := range expected {
+		if tokens[i].Type != tt.expectedType || tokens[i].Literal != tt.expectedLiteral {
+			t.Errorf("tokens[%d] wrong. expected=%s %q, got=%s %q",
+				i, tt.expectedType, tt.expectedLiteral, tokens[i].Type, tokens[i].Literal)
+		}
+	}
+
+	if len(errs) != 1 {
+		t.Fatalf("wrong number of errors. expected=1, got=%d (%v)", len(errs), errs)
+	}
+	expectedMsg := `1:11: illegal character '&' (did you mean "&&"?)`
+	if errs[0].Error() != expectedMsg {
+		t.Errorf("wrong error. expected=%q, got=%q", expectedMsg, errs[0].Error())
+	}
+}
+
+func TestAllStopsEarly(t *testing.T) {
+	l := New("a b c d")
+
+	var literals []string
+	l.All()(func(tok token.Token) bool {
+		literals = append(literals, tok.Literal)
+		return tok.Literal != "b"
+	})
+
+	if len(literals) != 2 {
+		t.Fatalf("wrong tokens. expected=[a b], got=%v", literals)
+	}
+
+	// 途中でやめても、残りは続きから読める
+	tok := l.NextToken()
+	if tok.Literal != "c" {
+		t.Errorf("next token wrong. expected=%q, got=%q", "c", tok.Literal)
+	}
+}
+
+func TestTokens(t *testing.T) {
+	l := New("a b c")
+	l.NextToken()
+
+	// 残りのトークンだけを返す
+	tokens := l.Tokens()
+	if len(tokens) != 2 || tokens[0].Literal != "b" || tokens[1].Literal != "c" {
+		t.Fatalf("wrong tokens. expected=[b c], got=%v", tokens)
+	}
+
+	if tok := l.NextToken(); tok.Type != token.EOF {
+		t.Errorf("expected EOF after Tokens, got=%s", tok.Type)
+	}
+}
+
+func TestShebang(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedPos     token.Position
+	}{
+		{"#!/usr/bin/env monkey\nlet", token.LET, "let", token.Position{Offset: 22, Line: 2, Column: 1}},
+		{"#!/usr/bin/env monkey", token.EOF, "", token.Position{Offset: 21, Line: 1, Column: 22}},
+		// 1行目の先頭にあるときだけ
+		{" #!", token.ILLEGAL, "#", token.Position{Offset: 1, Line: 1, Column: 2}},
+		{"x\n#!", token.IDENT, "x", token.Position{Offset: 0, Line: 1, Column: 1}},
+	}
+
+	for i, tt := range tests {
+		tok := New(tt.input).NextToken()
+
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Errorf("tests[%d] - token wrong. expected=%s %q, got=%s %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+
+		if tok.Pos != tt.expectedPos {
+			t.Errorf("tests[%d] - pos wrong. expected=%s, got=%s", i, tt.expectedPos, tok.Pos)
+		}
+	}
+}
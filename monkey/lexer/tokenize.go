@@ -0,0 +1,47 @@
+package lexer
+
+import (
+	"monkey/token"
+)
+
+// 残りのトークンを順に yield に渡すイテレータ
+// EOF に着くか yield が false を返したところで終わり、EOF トークン自体は渡さない
+//
+// 型は iter.Seq[token.Token] と同じなので、Go 1.23 以降のモジュールからは
+//
+//	for tok := range l.All() {
+//		...
+//	}
+//
+// と書ける。このモジュール自体は go 1.17 のままにしているので、中では Tokens を使う
+func (l *Lexer) All() func(yield func(token.Token) bool) {
+	return func(yield func(token.Token) bool) {
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+			if !yield(tok) {
+				return
+			}
+		}
+	}
+}
+
+// 残りのトークンをすべて読んで返す (EOF トークンは含めない)
+func (l *Lexer) Tokens() []token.Token {
+	var tokens []token.Token
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		tokens = append(tokens, tok)
+	}
+	return tokens
+}
+
+// ソースコード全体をトークンの列にする
+// EOF トークンは含めない。字句のエラーはトークンとは別にまとめて返す
+func Tokenize(src string, opts ...Option) ([]token.Token, []error) {
+	l := New(src, opts...)
+	tokens := l.Tokens()
+
+	var errs []error
+	for _, err := range l.Errors() {
+		errs = append(errs, err)
+	}
+	return tokens, errs
+}
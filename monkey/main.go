@@ -1,13 +1,92 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
 	"monkey/repl"
 	"os"
 	"os/user"
+	"path/filepath"
 )
 
+// 終了コード
+// パイプラインから失敗の種類が分かるように分けておく
+const (
+	exitOK           = 0
+	exitRuntimeError = 1 // 実行時のエラーや、ファイルが読めないとき
+	exitUsage        = 2 // コマンドラインの使い方が間違っている
+	exitLexError     = 3 // 字句解析のエラー
+	exitParseError   = 4 // 構文解析のエラー
+)
+
+const usage = `usage:
+  monkey [--mode=eval|tokens|ast|sexpr]
+                            start the REPL (or run stdin when it is piped)
+  monkey run <file>         run a script file
+  monkey -e <expr>          evaluate an expression and print the result
+  monkey tokens [--format=table|json] <file>
+  monkey ast [--format=tree|sexpr|dot] <file>
+`
+
 func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	// サブコマンドがあればそちらを実行する
+	if len(args) > 0 {
+		switch args[0] {
+		case "run":
+			return runScript(args[1:], stdout, stderr)
+		case "tokens":
+			return runTokens(args[1:], stdout, stderr)
+		case "ast":
+			return runAST(args[1:], stdout, stderr)
+		}
+	}
+
+	fs := flag.NewFlagSet("monkey", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprint(stderr, usage) }
+	expr := fs.String("e", "", "evaluate `expr` and print the result")
+	modeName := fs.String("mode", "eval", "initial REPL `mode`: eval, tokens, ast or sexpr")
+
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "unknown command %q\n", fs.Arg(0))
+		fs.Usage()
+		return exitUsage
+	}
+	mode, err := repl.ParseMode(*modeName)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+
+	exprSet := false
+	fs.Visit(func(f *flag.Flag) { exprSet = exprSet || f.Name == "e" })
+	if exprSet {
+		return execute("<expr>", *expr, stdout, stderr, true)
+	}
+
+	// パイプやリダイレクトで渡されたときはスクリプトとして実行する
+	if !repl.IsTerminal(stdin) {
+		src, err := io.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitRuntimeError
+		}
+		return execute("<stdin>", string(src), stdout, stderr, false)
+	}
+
+	startREPL(stdin, stdout, mode)
+	return exitOK
+}
+
+func startREPL(in io.Reader, out io.Writer, mode repl.Mode) {
 	user, err := user.Current()
 	// userを返す
 	// こんな値になるらしい https://blog.suganoo.net/entry/2018/09/11/185131
@@ -21,7 +100,8 @@ func main() {
 		panic(err)
 	}
 
-	fmt.Printf("Hello %s! This is Monley programming language!\n", user.Username)
-	fmt.Print("Feel free to type in commands\n")
-	repl.Start(os.Stdin, os.Stdout)
+	fmt.Fprintf(out, "Hello %s! This is Monley programming language!\n", user.Username)
+	fmt.Fprint(out, "Feel free to type in commands\n")
+	history := filepath.Join(user.HomeDir, ".monkey_history")
+	repl.Start(in, out, repl.WithMode(mode), repl.WithHistoryFile(history))
 }
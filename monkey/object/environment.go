@@ -0,0 +1,45 @@
+package object
+
+import "sort"
+
+// 変数名と値の束縛を保持する
+type Environment struct {
+	store map[string]Object
+	outer *Environment // 外側のスコープ (一番外なら nil)
+}
+
+func NewEnvironment() *Environment {
+	s := make(map[string]Object)
+	return &Environment{store: s}
+}
+
+// 関数呼び出しのたびに、外側の環境を包んだ新しい環境を作る
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+	env := NewEnvironment()
+	env.outer = outer
+	return env
+}
+
+// 見つからなければ外側の環境を順に探す
+func (e *Environment) Get(name string) (Object, bool) {
+	obj, ok := e.store[name]
+	if !ok && e.outer != nil {
+		obj, ok = e.outer.Get(name)
+	}
+	return obj, ok
+}
+
+func (e *Environment) Set(name string, val Object) Object {
+	e.store[name] = val
+	return val
+}
+
+// この環境で束縛されている名前を並べて返す (外側の環境は含めない)
+func (e *Environment) Names() []string {
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,56 @@
+package object
+
+import "testing"
+
+func TestStringHashKey(t *testing.T) {
+	hello1 := &String{Value: "Hello World"}
+	hello2 := &String{Value: "Hello World"}
+	diff1 := &String{Value: "My name is johnny"}
+	diff2 := &String{Value: "My name is johnny"}
+
+	if hello1.HashKey() != hello2.HashKey() {
+		t.Errorf("strings with same content have different hash keys")
+	}
+
+	if diff1.HashKey() != diff2.HashKey() {
+		t.Errorf("strings with same content have different hash keys")
+	}
+
+	if hello1.HashKey() == diff1.HashKey() {
+		t.Errorf("strings with different content have same hash keys")
+	}
+}
+
+func TestHashInspect(t *testing.T) {
+	one := &String{Value: "one"}
+	two := &String{Value: "two"}
+
+	hash := &Hash{Pairs: map[HashKey]HashPair{
+		two.HashKey(): {Key: two, Value: &Integer{Value: 2}},
+		one.HashKey(): {Key: one, Value: &Integer{Value: 1}},
+	}}
+
+	if hash.Inspect() != "{one: 1, two: 2}" {
+		t.Errorf("hash.Inspect() wrong. got=%q", hash.Inspect())
+	}
+}
+
+func TestEnvironmentNames(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("b", &Integer{Value: 1})
+	outer.Set("a", &Integer{Value: 2})
+
+	env := NewEnclosedEnvironment(outer)
+	env.Set("c", &Integer{Value: 3})
+
+	names := outer.Names()
+	if len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Errorf("outer.Names() wrong. expected=[a b], got=%v", names)
+	}
+
+	// 外側の名前は含めない
+	names = env.Names()
+	if len(names) != 1 || names[0] != "c" {
+		t.Errorf("env.Names() wrong. expected=[c], got=%v", names)
+	}
+}
@@ -0,0 +1,196 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"monkey/token"
+	"monkey/width"
+	"sort"
+	"strings"
+)
+
+// 診断の重大度
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityInfo
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// JSONでは数値ではなく "error" などの文字列で出す
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(text []byte) error {
+	for _, v := range []Severity{SeverityError, SeverityWarning, SeverityInfo} {
+		if v.String() == string(text) {
+			*s = v
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown severity %q", text)
+}
+
+// 診断の種類を表すコード
+// ツールから機械的に判別できるように固定の文字列にしておく
+const (
+	CodeLexError        = "lexical-error"       // 字句解析器が見つけたエラー
+	CodeUnexpectedToken = "unexpected-token"    // 期待したトークンと違う
+	CodeNoPrefixParseFn = "no-prefix-parse-fn"  // 式の先頭に置けないトークン
+	CodeInvalidInteger  = "invalid-integer"     // 整数として読めないリテラル
+	CodeInvalidFloat    = "invalid-float"       // 小数として読めないリテラル
+	CodeNumberRange     = "number-out-of-range" // 64bitに収まらない数値リテラル
+)
+
+// Pos から End までを NewText に置き換えれば直る、という提案
+// Pos == End なら挿入になる
+type SuggestedFix struct {
+	Message string         `json:"message"`
+	Pos     token.Position `json:"pos"`
+	End     token.Position `json:"end"`
+	NewText string         `json:"newText"`
+}
+
+// パース中に見つかった問題1件分
+type Diagnostic struct {
+	Severity Severity       `json:"severity"`
+	Code     string         `json:"code"`
+	Pos      token.Position `json:"pos"` // 問題のある範囲の先頭
+	End      token.Position `json:"end"` // 問題のある範囲の直後
+	Message  string         `json:"message"`
+	Fix      *SuggestedFix  `json:"fix,omitempty"`
+}
+
+// file:line:column: message の形式
+func (d *Diagnostic) Error() string {
+	return d.Pos.String() + ": " + d.Message
+}
+
+// src の該当行を取り出して、問題のある範囲の下に ^~~ を引く
+//
+//	let x 5;
+//	      ^
+func (d *Diagnostic) Excerpt(src string) string {
+	if !d.Pos.IsValid() {
+		return ""
+	}
+
+	if d.Pos.Offset > len(src) {
+		return ""
+	}
+
+	// 行頭と行末を探す
+	lineStart := strings.LastIndexByte(src[:d.Pos.Offset], '\n') + 1
+	lineEnd := strings.IndexByte(src[lineStart:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(src)
+	} else {
+		lineEnd += lineStart
+	}
+	line := strings.TrimRight(src[lineStart:lineEnd], "\r")
+
+	// 範囲が次の行にまたがる場合はこの行の終わりまで
+	end := d.Pos.Offset
+	if d.End.Offset > d.Pos.Offset {
+		end = d.End.Offset
+	}
+	if end > lineStart+len(line) {
+		end = lineStart + len(line)
+	}
+
+	// 全角文字は端末で2マス使うので、位置も幅もマスの数で数える
+	underline := width.String(src[d.Pos.Offset:end])
+	if underline < 1 {
+		underline = 1
+	}
+
+	var out bytes.Buffer
+	out.WriteString(line)
+	out.WriteString("\n")
+	// タブはタブのまま残すと表示位置がずれない
+	for _, ch := range src[lineStart:d.Pos.Offset] {
+		if ch == '\t' {
+			out.WriteRune('\t')
+		} else {
+			out.WriteString(strings.Repeat(" ", width.Rune(ch)))
+		}
+	}
+	out.WriteString("^")
+	out.WriteString(strings.Repeat("~", underline-1))
+
+	return out.String()
+}
+
+// 診断のリスト
+// error を実装しているので、そのまま error として返せる
+type ErrorList []*Diagnostic
+
+func (el ErrorList) Len() int      { return len(el) }
+func (el ErrorList) Swap(i, j int) { el[i], el[j] = el[j], el[i] }
+func (el ErrorList) Less(i, j int) bool {
+	a, b := el[i].Pos, el[j].Pos
+	if a.Filename != b.Filename {
+		return a.Filename < b.Filename
+	}
+	if a.Offset != b.Offset {
+		return a.Offset < b.Offset
+	}
+	return el[i].Message < el[j].Message
+}
+
+// ファイル名、位置の順に並べる
+func (el ErrorList) Sort() {
+	sort.Sort(el)
+}
+
+func (el ErrorList) Error() string {
+	switch len(el) {
+	case 0:
+		return "no errors"
+	case 1:
+		return el[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", el[0].Error(), len(el)-1)
+}
+
+// 診断が無ければ nil を返す
+// ErrorList のまま返すと nil にならないので error として扱うときはこちらを使う
+func (el ErrorList) Err() error {
+	if len(el) == 0 {
+		return nil
+	}
+	return el
+}
+
+// 人が読む用に、診断ごとにソースの該当行を添えて並べる
+func (el ErrorList) Render(src string) string {
+	var out bytes.Buffer
+
+	for _, d := range el {
+		out.WriteString(fmt.Sprintf("%s: %s: %s\n", d.Pos, d.Severity, d.Message))
+		if excerpt := d.Excerpt(src); excerpt != "" {
+			out.WriteString(excerpt)
+			out.WriteString("\n")
+		}
+		if d.Fix != nil {
+			out.WriteString("hint: " + d.Fix.Message + "\n")
+		}
+	}
+
+	return out.String()
+}
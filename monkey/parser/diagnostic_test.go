@@ -0,0 +1,155 @@
+package parser
+
+import (
+	"encoding/json"
+	"monkey/lexer"
+	"monkey/token"
+	"strings"
+	"testing"
+)
+
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedCode string
+		expectedPos  string
+		expectedEnd  string
+		expectedFix  string
+	}{
+		{"let x 5;", CodeUnexpectedToken, "1:7", "1:8", "="},
+		{"let = 5;", CodeUnexpectedToken, "1:5", "1:6", ""},
+		{"1 +\n  ;", CodeNoPrefixParseFn, "2:3", "2:4", ""},
+		{"99999999999999999999", CodeNumberRange, "1:1", "1:21", ""},
+		{"x + 0b102", CodeInvalidInteger, "1:5", "1:10", ""},
+		{"1e", CodeInvalidFloat, "1:1", "1:3", ""},
+		{"1e999", CodeNumberRange, "1:1", "1:6", ""},
+		{"{1:2", CodeUnexpectedToken, "1:5", "1:5", "}"},
+		{"{1:2, 3:4 let", CodeUnexpectedToken, "1:11", "1:14", "}"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("input %q: expected errors, got none", tt.input)
+		}
+
+		d := errors[0]
+		if d.Severity != SeverityError {
+			t.Errorf("input %q: severity wrong. got=%s", tt.input, d.Severity)
+		}
+		if d.Code != tt.expectedCode {
+			t.Errorf("input %q: code wrong. expected=%s, got=%s", tt.input, tt.expectedCode, d.Code)
+		}
+		if d.Pos.String() != tt.expectedPos {
+			t.Errorf("input %q: pos wrong. expected=%s, got=%s", tt.input, tt.expectedPos, d.Pos)
+		}
+		if d.End.String() != tt.expectedEnd {
+			t.Errorf("input %q: end wrong. expected=%s, got=%s", tt.input, tt.expectedEnd, d.End)
+		}
+
+		if tt.expectedFix == "" {
+			if d.Fix != nil {
+				t.Errorf("input %q: expected no fix, got %+v", tt.input, d.Fix)
+			}
+			continue
+		}
+		if d.Fix == nil {
+			t.Fatalf("input %q: expected fix %q, got none", tt.input, tt.expectedFix)
+		}
+		if d.Fix.NewText != tt.expectedFix {
+			t.Errorf("input %q: fix wrong. expected=%q, got=%q", tt.input, tt.expectedFix, d.Fix.NewText)
+		}
+	}
+}
+
+func TestErrorListSortAndError(t *testing.T) {
+	el := ErrorList{
+		{Pos: token.Position{Offset: 10, Line: 2, Column: 3}, Message: "second"},
+		{Pos: token.Position{Offset: 2, Line: 1, Column: 3}, Message: "first"},
+	}
+	el.Sort()
+
+	if el[0].Message != "first" || el[1].Message != "second" {
+		t.Fatalf("ErrorList not sorted. got=%q, %q", el[0].Message, el[1].Message)
+	}
+
+	expected := "1:3: first (and 1 more errors)"
+	if el.Error() != expected {
+		t.Errorf("ErrorList.Error() wrong. expected=%q, got=%q", expected, el.Error())
+	}
+
+	if (ErrorList{}).Err() != nil {
+		t.Errorf("empty ErrorList.Err() should be nil")
+	}
+}
+
+func TestErrorListRender(t *testing.T) {
+	input := "let a = 1;\n\tlet x 5;"
+
+	p := New(lexer.New(input, lexer.WithFilename("test.mk")))
+	p.ParseProgram()
+
+	expected := "test.mk:2:8: error: expected next token to be =, got INT instead\n" +
+		"\tlet x 5;\n" +
+		"\t      ^\n" +
+		"hint: insert \"=\"\n"
+
+	actual := p.Errors()[:1].Render(input)
+	if actual != expected {
+		t.Errorf("Render wrong.\nexpected=%q\ngot=     %q", expected, actual)
+	}
+}
+
+func TestExcerptWideCharacters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let 変数 = ;", "let 変数 = ;\n           ^"},
+		{"let 名前 名前;", "let 名前 名前;\n         ^~~~"},
+		{"let x = \"あい", "let x = \"あい\n        ^~~~~"},
+		{"\tlet あ 1;", "\tlet あ 1;\n\t       ^"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("input %q: expected errors", tt.input)
+		}
+
+		actual := errors[0].Excerpt(tt.input)
+		if actual != tt.expected {
+			t.Errorf("input %q: excerpt wrong.\nexpected=%q\ngot=     %q", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestDiagnosticJSON(t *testing.T) {
+	p := New(lexer.New("let x 5;"))
+	p.ParseProgram()
+
+	b, err := json.Marshal(p.Errors()[0])
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %s", err)
+	}
+
+	for _, want := range []string{`"severity":"error"`, `"code":"unexpected-token"`, `"line":1`, `"newText":"="`} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("json %s does not contain %s", b, want)
+		}
+	}
+
+	var d Diagnostic
+	if err := json.Unmarshal(b, &d); err != nil {
+		t.Fatalf("json.Unmarshal failed: %s", err)
+	}
+	if d.Severity != SeverityError || d.Code != CodeUnexpectedToken {
+		t.Errorf("round trip wrong. got=%+v", d)
+	}
+}
@@ -0,0 +1,143 @@
+package parser
+
+import (
+	"fmt"
+	"monkey/token"
+)
+
+// 優先度
+// 下にあるものほど強く結びつく
+const (
+	_ int = iota // _ を0にしてこのあとの定数に1から連番を振る
+	LOWEST
+	LOGICAL_OR  // ||
+	LOGICAL_AND // &&
+	EQUALS      // ==
+	LESSGREATER //> or <
+	SUM         //+
+	PRODUCT     //* / %
+	PREFIX      //-X !X
+	CALL        //function(X)
+	INDEX       //array[index]
+)
+
+// 同じ優先度の演算子が並んだときにどちらから結びつくか
+type Associativity int
+
+const (
+	LeftAssoc  Associativity = iota // a - b - c は (a - b) - c
+	RightAssoc                      // a ** b ** c は a ** (b ** c)
+)
+
+func (a Associativity) String() string {
+	switch a {
+	case LeftAssoc:
+		return "left"
+	case RightAssoc:
+		return "right"
+	default:
+		return fmt.Sprintf("Associativity(%d)", int(a))
+	}
+}
+
+// 演算子が被演算子のどこに置かれるか
+type Fixity int
+
+const (
+	Prefix  Fixity = iota // -X
+	Infix                 // X + Y
+	Postfix               // X(Y), X[Y] のように左の式の後ろに続くもの
+)
+
+func (f Fixity) String() string {
+	switch f {
+	case Prefix:
+		return "prefix"
+	case Infix:
+		return "infix"
+	case Postfix:
+		return "postfix"
+	default:
+		return fmt.Sprintf("Fixity(%d)", int(f))
+	}
+}
+
+// 演算子1つ分の定義
+type Operator struct {
+	Token         token.TokenType
+	Precedence    int
+	Associativity Associativity
+	Fixity        Fixity
+}
+
+// 演算子の一覧
+// New はこの表から prefixParseFn / infixParseFn と優先度を組み立てる
+// 演算子を増やすときはここに1行足す
+var operators = []Operator{
+	{token.BANG, PREFIX, RightAssoc, Prefix},
+	{token.MINUS, PREFIX, RightAssoc, Prefix},
+
+	{token.OR, LOGICAL_OR, LeftAssoc, Infix},
+	{token.AND, LOGICAL_AND, LeftAssoc, Infix},
+	{token.EQ, EQUALS, LeftAssoc, Infix},
+	{token.NOT_EQ, EQUALS, LeftAssoc, Infix},
+	{token.LT, LESSGREATER, LeftAssoc, Infix},
+	{token.GT, LESSGREATER, LeftAssoc, Infix},
+	{token.LT_EQ, LESSGREATER, LeftAssoc, Infix},
+	{token.GT_EQ, LESSGREATER, LeftAssoc, Infix},
+	{token.PLUS, SUM, LeftAssoc, Infix},
+	{token.MINUS, SUM, LeftAssoc, Infix},
+	{token.ASTERISK, PRODUCT, LeftAssoc, Infix},
+	{token.SLASH, PRODUCT, LeftAssoc, Infix},
+	{token.PERCENT, PRODUCT, LeftAssoc, Infix},
+
+	{token.LPAREN, CALL, LeftAssoc, Postfix},    // add(1, 2)
+	{token.LBRACKET, INDEX, LeftAssoc, Postfix}, // array[1]
+}
+
+// 演算子の一覧を返す
+// 返すのはコピーなので、書き換えてもパーサーには影響しない
+func Operators() []Operator {
+	return append([]Operator(nil), operators...)
+}
+
+// 表の内容でパース関数と優先度を登録する
+func (p *Parser) registerOperators(operators []Operator) {
+	for _, op := range operators {
+		switch op.Fixity {
+		case Prefix:
+			p.registerPrefix(op.Token, p.parsePrefixExpression)
+			p.prefixPrecedences[op.Token] = op.Precedence
+			continue
+		case Infix:
+			p.registerInfix(op.Token, p.infixOperatorParseFn(op.Token))
+		case Postfix:
+			p.registerInfix(op.Token, p.postfixParseFn(op.Token))
+		}
+
+		p.precedences[op.Token] = op.Precedence
+		p.rightAssoc[op.Token] = op.Associativity == RightAssoc
+	}
+}
+
+// && と || は右辺を評価しないことがあるので別のノードにする
+func (p *Parser) infixOperatorParseFn(t token.TokenType) infixParseFn {
+	switch t {
+	case token.AND, token.OR:
+		return p.parseLogicalExpression
+	default:
+		return p.parseInfixExpression
+	}
+}
+
+// 後置の演算子はそれぞれ読み方が違うので個別に対応づける
+func (p *Parser) postfixParseFn(t token.TokenType) infixParseFn {
+	switch t {
+	case token.LPAREN:
+		return p.parseCallExpression
+	case token.LBRACKET:
+		return p.parseIndexExpression
+	default:
+		panic(fmt.Sprintf("parser: no postfix parse function for %s", t))
+	}
+}
@@ -0,0 +1,117 @@
+package parser
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/token"
+	"testing"
+)
+
+// 表のすべての行が、その通りにパーサへ登録されているかをチェック
+func TestOperatorTable(t *testing.T) {
+	p := New(lexer.New(""))
+
+	for _, op := range Operators() {
+		name := fmt.Sprintf("%s %s", op.Fixity, op.Token)
+
+		switch op.Fixity {
+		case Prefix:
+			if p.prefixParseFn[op.Token] == nil {
+				t.Errorf("%s: no prefix parse function registered", name)
+			}
+			if p.prefixPrecedences[op.Token] != op.Precedence {
+				t.Errorf("%s: prefix precedence wrong. expected=%d, got=%d", name, op.Precedence, p.prefixPrecedences[op.Token])
+			}
+			testPrefixOperator(t, op)
+		case Infix, Postfix:
+			if p.infixParseFn[op.Token] == nil {
+				t.Errorf("%s: no infix parse function registered", name)
+			}
+			if p.precedences[op.Token] != op.Precedence {
+				t.Errorf("%s: precedence wrong. expected=%d, got=%d", name, op.Precedence, p.precedences[op.Token])
+			}
+			if op.Fixity == Infix {
+				testInfixOperator(t, op)
+			}
+		default:
+			t.Errorf("%s: unknown fixity", name)
+		}
+	}
+}
+
+// -a + b のように、前置の演算子は中置の演算子より先に結びつく
+func testPrefixOperator(t *testing.T, op Operator) {
+	input := fmt.Sprintf("%sa + b", op.Token)
+	expected := fmt.Sprintf("((%sa) + b)", op.Token)
+
+	testOperatorParsing(t, input, expected)
+}
+
+// 結合の向きと、同じ優先度の演算子が並んだときの結びつき方をチェック
+func testInfixOperator(t *testing.T, op Operator) {
+	input := fmt.Sprintf("a %s b %s c", op.Token, op.Token)
+	expected := fmt.Sprintf("((a %s b) %s c)", op.Token, op.Token)
+	if op.Associativity == RightAssoc {
+		expected = fmt.Sprintf("(a %s (b %s c))", op.Token, op.Token)
+	}
+	testOperatorParsing(t, input, expected)
+
+	// 他の中置演算子と組み合わせたときに優先度の通りに結びつく
+	for _, other := range Operators() {
+		if other.Fixity != Infix || other.Precedence == op.Precedence {
+			continue
+		}
+
+		input := fmt.Sprintf("a %s b %s c", op.Token, other.Token)
+		expected := fmt.Sprintf("((a %s b) %s c)", op.Token, other.Token)
+		if other.Precedence > op.Precedence {
+			expected = fmt.Sprintf("(a %s (b %s c))", op.Token, other.Token)
+		}
+		testOperatorParsing(t, input, expected)
+	}
+}
+
+func testOperatorParsing(t *testing.T, input, expected string) {
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if program.String() != expected {
+		t.Errorf("input %q: expected=%q, got=%q", input, expected, program.String())
+	}
+}
+
+func TestOperatorsReturnsCopy(t *testing.T) {
+	ops := Operators()
+	for i := range ops {
+		ops[i].Precedence = LOWEST
+	}
+
+	// 返された表を書き換えてもパーサーの優先度は変わらない
+	testOperatorParsing(t, "a + b * c", "(a + (b * c))")
+	for _, op := range Operators() {
+		if op.Precedence == LOWEST {
+			t.Errorf("%s %s: table was modified through the returned slice", op.Fixity, op.Token)
+		}
+	}
+}
+
+func TestRightAssociativeOperator(t *testing.T) {
+	// 右結合の演算子を足した表でパースする
+	operators := append(Operators(), Operator{token.ASSIGN, PRODUCT + 1, RightAssoc, Infix})
+
+	p := newParser(lexer.New("a = b = c * 2"), operators)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	expected := "((a = (b = c)) * 2)"
+	if program.String() != expected {
+		t.Errorf("expected=%q, got=%q", expected, program.String())
+	}
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	if _, ok := stmt.Expression.(*ast.InfixExpression); !ok {
+		t.Errorf("exp is not *ast.InfixExpression. got=%T", stmt.Expression)
+	}
+}
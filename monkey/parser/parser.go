@@ -1,36 +1,15 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"monkey/ast"
 	"monkey/lexer"
 	"monkey/token"
 	"strconv"
+	"unicode"
 )
 
-const (
-	_ int = iota // _ を0にしてこのあとの定数に1から連番を振る
-	LOWEST
-	EQUALS      // ==
-	LESSGREATER //> or <
-	SUM         //+
-	PRODUCT     //*
-	PREFIX      //-X !X
-	CALL        //function(X)
-)
-
-// TokenType と優先度のmap
-var precedence = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NOT_EQ:   EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PREFIX,
-}
-
 type (
 	prefixParseFn func() ast.Expression
 	infixParseFn  func(ast.Expression) ast.Expression
@@ -42,24 +21,88 @@ type Parser struct {
 	curToken  token.Token // 今のtoken
 	peekToken token.Token // 次のtoken
 
+	lexErrors int // 診断に移し終えた字句解析器のエラーの数
+
 	prefixParseFn map[token.TokenType]prefixParseFn // key が token.TokenType で value が prefixParseFn
 	infixParseFn  map[token.TokenType]infixParseFn
 
-	errors []string
+	precedences       map[token.TokenType]int  // 中置・後置の演算子の優先度
+	prefixPrecedences map[token.TokenType]int  // 前置の演算子の優先度
+	rightAssoc        map[token.TokenType]bool // 右結合の演算子
+
+	errors ErrorList
+
+	// エラーを見つけてから次の文の区切りまで読み飛ばすまでの間 true
+	// この間のエラーは最初のエラーの巻き添えなので記録しない
+	panicMode bool
 }
 
-func (p *Parser) Errors() []string {
+func (p *Parser) Errors() ErrorList {
 	return p.errors
 }
 
+// pos から end までの範囲についてエラーを記録する
+// 呼び出し側で Fix を付けられるように記録した診断を返す
+func (p *Parser) addError(code string, pos, end token.Position, format string, a ...interface{}) *Diagnostic {
+	if p.panicMode {
+		// 巻き添えのエラーは捨てる
+		return &Diagnostic{}
+	}
+	p.panicMode = true
+
+	d := &Diagnostic{
+		Severity: SeverityError,
+		Code:     code,
+		Pos:      pos,
+		End:      end,
+		Message:  fmt.Sprintf(format, a...),
+	}
+	p.errors = append(p.errors, d)
+	return d
+}
+
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	d := p.addError(CodeUnexpectedToken, p.peekToken.Pos, p.peekToken.End,
+		"expected next token to be %s, got %s instead", t, p.peekToken.Type)
+
+	// 記号が足りないだけなら、今のトークンの直後に挿入すれば直る
+	if isPunctuation(t) {
+		d.Fix = &SuggestedFix{
+			Message: fmt.Sprintf("insert %q", string(t)),
+			Pos:     p.curToken.End,
+			End:     p.curToken.End,
+			NewText: string(t),
+		}
+	}
+}
+
+// = や ; のように TokenType がそのまま記号になっているもの
+func isPunctuation(t token.TokenType) bool {
+	if len(t) == 0 {
+		return false
+	}
+	return !unicode.IsLetter(rune(t[0]))
 }
 
 func (p *Parser) NextToken() {
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
+	// コメントを token.COMMENT として返す字句解析器でも、パーサは読み飛ばす
+	for p.peekToken.Type == token.COMMENT {
+		p.peekToken = p.l.NextToken()
+	}
+	p.reportLexerErrors()
+}
+
+// 字句解析器のエラーを診断に移す
+// peekToken の分まで移すと1つ前の文のエラーになってしまうので、curToken までの分だけにする
+func (p *Parser) reportLexerErrors() {
+	errs := p.l.Errors()
+	for p.lexErrors < len(errs) && errs[p.lexErrors].Pos.Offset < p.peekToken.Pos.Offset {
+		e := errs[p.lexErrors]
+		p.lexErrors++
+		p.addError(CodeLexError, e.Pos, e.End, "%s", e.Msg)
+	}
 }
 func (p *Parser) ParseProgram() *ast.Program {
 	// 空の Program structを新規作成
@@ -77,6 +120,52 @@ func (p *Parser) ParseProgram() *ast.Program {
 }
 
 func (p *Parser) parseStatement() ast.Statement {
+	start := p.curToken
+
+	stmt := p.parseStatementNode()
+
+	// 途中でエラーになった文は中途半端なので捨てて、次の文の区切りまで読み飛ばす
+	if p.panicMode {
+		p.synchronize()
+		p.panicMode = false
+		return &ast.BadStatement{Token: start, From: start.Pos, To: p.curToken.End}
+	}
+
+	return stmt
+}
+
+// 次の文が始まるところまでトークンを読み飛ばす
+// ; か、次のトークンが let / return / } のところで止まる
+// 途中の { } の中身は対応が取れるまでまとめて読み飛ばす
+func (p *Parser) synchronize() {
+	depth := 0
+
+	for !p.curTokenIs(token.EOF) {
+		switch p.curToken.Type {
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			if depth > 0 {
+				depth--
+			}
+		case token.SEMICOLON:
+			if depth == 0 {
+				return
+			}
+		}
+
+		if depth == 0 {
+			switch p.peekToken.Type {
+			case token.LET, token.RETURN, token.RBRACE, token.EOF:
+				return
+			}
+		}
+
+		p.NextToken()
+	}
+}
+
+func (p *Parser) parseStatementNode() ast.Statement {
 	switch p.curToken.Type {
 	case token.LET:
 		return p.parseLetStatement()
@@ -105,10 +194,12 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 		// 期待ハズレは全部nilを返す
 	}
 	// = なら次へ行く
+	p.NextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
 
-	// FIXME: セミコロンまで読み飛ばし
-	// Valueに値が入っていない
-	for !p.curTokenIs(token.SEMICOLON) {
+	// セミコロンは省略可能
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.NextToken()
 	}
 
@@ -122,8 +213,10 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 
 	p.NextToken()
 
-	// FIXME: セミコロンまで読み飛ばし
-	for !p.curTokenIs(token.SEMICOLON) {
+	stmt.ReturnValue = p.parseExpression(LOWEST)
+
+	// セミコロンは省略可能
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.NextToken()
 	}
 
@@ -135,7 +228,7 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 
 	stmt.Expression = p.parseExpression(LOWEST)
 
-	// FIXME: セミコロンまで読み飛ばし
+	// セミコロンは省略可能
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.NextToken()
 	}
@@ -147,7 +240,7 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 	prefix := p.prefixParseFn[p.curToken.Type]
 	if prefix == nil {
 		p.noPrefixParseFnError(p.curToken.Type)
-		return nil
+		return p.badExpression()
 	}
 	leftExp := prefix()
 
@@ -166,8 +259,8 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 }
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.addError(CodeNoPrefixParseFn, p.curToken.Pos, p.curToken.End,
+		"no prefix parse function for %s found", t)
 }
 
 func (p *Parser) curTokenIs(t token.TokenType) bool {
@@ -187,13 +280,13 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 }
 
 func (p *Parser) peekPrecedence() int {
-	if p, ok := precedence[p.peekToken.Type]; ok {
+	if p, ok := p.precedences[p.peekToken.Type]; ok {
 		return p
 	}
 	return LOWEST
 }
 func (p *Parser) curPrecedence() int {
-	if p, ok := precedence[p.curToken.Type]; ok {
+	if p, ok := p.precedences[p.curToken.Type]; ok {
 		return p
 	}
 	return LOWEST
@@ -206,30 +299,307 @@ func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
 	p.infixParseFn[tokenType] = fn
 }
 
+// パースできなかった式の代わりに今のトークンの範囲を埋めておく
+func (p *Parser) badExpression() ast.Expression {
+	return &ast.BadExpression{Token: p.curToken, From: p.curToken.Pos, To: p.curToken.End}
+}
+
 func (p *Parser) parseIdentifier() ast.Expression {
 	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 }
 
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.curToken}
+	// base を 0 にすると 0x 0o 0b と _ を解釈してくれる
 	val64, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		p.addError(CodeNumberRange, p.curToken.Pos, p.curToken.End,
+			"integer literal %s out of range", p.curToken.Literal)
+		return p.badExpression()
+	}
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
-		return nil
+		p.addError(CodeInvalidInteger, p.curToken.Pos, p.curToken.End,
+			"could not parse %q as integer", p.curToken.Literal)
+		return p.badExpression()
 	}
 	lit.Value = val64
 
 	return lit
 }
 
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curToken}
+
+	val, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		p.addError(CodeNumberRange, p.curToken.Pos, p.curToken.End,
+			"float literal %s out of range", p.curToken.Literal)
+		return p.badExpression()
+	}
+	if err != nil {
+		p.addError(CodeInvalidFloat, p.curToken.Pos, p.curToken.End,
+			"could not parse %q as float", p.curToken.Literal)
+		return p.badExpression()
+	}
+	lit.Value = val
+
+	return lit
+}
+
+func (p *Parser) parseStringLiteral() ast.Expression {
+	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+}
+
+// 字句解析器がすでにエラーを報告しているので、ここでは何も報告しない
+func (p *Parser) parseIllegal() ast.Expression {
+	return p.badExpression()
+}
+
+func (p *Parser) parseBoolean() ast.Expression {
+	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
+}
+
+// ( ) で囲まれた式
+// 括弧の中を LOWEST から読み直すことで優先度を上書きする
+func (p *Parser) parseGroupedExpression() ast.Expression {
+	p.NextToken()
+
+	exp := p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return p.badExpression()
+	}
+
+	return exp
+}
+
+func (p *Parser) parseIfExpression() ast.Expression {
+	expression := &ast.IfExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return p.badExpression()
+	}
+	p.NextToken()
+	expression.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return p.badExpression()
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return p.badExpression()
+	}
+	expression.Consequence = p.parseBlockStatement()
+
+	// else は省略可能
+	if p.peekTokenIs(token.ELSE) {
+		p.NextToken()
+
+		if !p.expectPeek(token.LBRACE) {
+			return p.badExpression()
+		}
+		expression.Alternative = p.parseBlockStatement()
+	}
+
+	return expression
+}
+
+// { から } までの文を読む
+// 呼ばれた時点で curToken は { 、終わった時点で } になっている
+func (p *Parser) parseBlockStatement() *ast.BlockStatement {
+	block := &ast.BlockStatement{Token: p.curToken}
+	block.Statements = []ast.Statement{}
+
+	p.NextToken()
+
+	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
+		stmt := p.parseStatement()
+		if stmt != nil {
+			block.Statements = append(block.Statements, stmt)
+		}
+		p.NextToken()
+	}
+
+	if p.curTokenIs(token.EOF) {
+		p.addError(CodeUnexpectedToken, p.curToken.Pos, p.curToken.End,
+			"expected next token to be %s, got %s instead", token.RBRACE, token.EOF)
+		return block
+	}
+	block.RBrace = p.curToken
+
+	return block
+}
+
+func (p *Parser) parseFunctionLiteral() ast.Expression {
+	lit := &ast.FunctionLiteral{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return p.badExpression()
+	}
+
+	lit.Parameters = p.parseFunctionParameters()
+	if lit.Parameters == nil {
+		return p.badExpression()
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return p.badExpression()
+	}
+	lit.Body = p.parseBlockStatement()
+
+	return lit
+}
+
+// (x, y, z) の変数名を読む
+// 失敗したら nil を返す
+func (p *Parser) parseFunctionParameters() []*ast.Identifier {
+	identifiers := []*ast.Identifier{}
+
+	// 引数なし
+	if p.peekTokenIs(token.RPAREN) {
+		p.NextToken()
+		return identifiers
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	identifiers = append(identifiers, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+
+	for p.peekTokenIs(token.COMMA) {
+		p.NextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		identifiers = append(identifiers, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	return identifiers
+}
+
+func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
+	exp := &ast.CallExpression{Token: p.curToken, Function: function}
+
+	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	if exp.Arguments == nil {
+		return p.badExpression()
+	}
+	exp.RParen = p.curToken
+
+	return exp
+}
+
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	array := &ast.ArrayLiteral{Token: p.curToken}
+
+	array.Elements = p.parseExpressionList(token.RBRACKET)
+	if array.Elements == nil {
+		return p.badExpression()
+	}
+	array.RBracket = p.curToken
+
+	return array
+}
+
+func (p *Parser) parseHashLiteral() ast.Expression {
+	hash := &ast.HashLiteral{Token: p.curToken}
+	hash.Pairs = []*ast.HashPair{}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.NextToken()
+		key := p.parseExpression(LOWEST)
+
+		if !p.expectPeek(token.COLON) {
+			return p.badExpression()
+		}
+
+		p.NextToken()
+		value := p.parseExpression(LOWEST)
+
+		hash.Pairs = append(hash.Pairs, &ast.HashPair{Key: key, Value: value})
+
+		// , が続かなければ最後の組なので、あとは } が来るはず
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.NextToken()
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return p.badExpression()
+	}
+	hash.RBrace = p.curToken
+
+	return hash
+}
+
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+
+	p.NextToken()
+	exp.Index = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RBRACKET) {
+		return p.badExpression()
+	}
+	exp.RBracket = p.curToken
+
+	return exp
+}
+
+// , 区切りの式を end まで読む
+// 失敗したら nil を返す
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	list := []ast.Expression{}
+
+	// 空のリスト
+	if p.peekTokenIs(end) {
+		p.NextToken()
+		return list
+	}
+
+	p.NextToken()
+	list = append(list, p.parseExpression(LOWEST))
+
+	for p.peekTokenIs(token.COMMA) {
+		p.NextToken()
+		p.NextToken()
+		list = append(list, p.parseExpression(LOWEST))
+	}
+
+	if !p.expectPeek(end) {
+		return nil
+	}
+
+	return list
+}
+
 func (p *Parser) parsePrefixExpression() ast.Expression {
 	expression := &ast.PrefixExpression{
 		Token:    p.curToken,
 		Operator: p.curToken.Literal,
 	}
+	precedence := p.prefixPrecedences[expression.Token.Type]
 	p.NextToken()
-	expression.Right = p.parseExpression(PREFIX)
+	expression.Right = p.parseExpression(precedence)
+	return expression
+}
+
+func (p *Parser) parseLogicalExpression(left ast.Expression) ast.Expression {
+	expression := &ast.LogicalExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+		Left:     left,
+	}
+	precedence := p.curPrecedence()
+	if p.rightAssoc[expression.Token.Type] {
+		precedence--
+	}
+	p.NextToken()
+	expression.Right = p.parseExpression(precedence)
 	return expression
 }
 
@@ -240,33 +610,48 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 		Left:     left,
 	}
 	precedence := p.curPrecedence() // operatorのprecedenceをとってる？それでいいのか？
+	// 右結合なら1つ弱くして、同じ優先度の演算子を右側に取り込む
+	if p.rightAssoc[expression.Token.Type] {
+		precedence--
+	}
 	p.NextToken()
 	expression.Right = p.parseExpression(precedence)
 	return expression
 }
 
 func New(l *lexer.Lexer) *Parser {
+	return newParser(l, operators)
+}
+
+// 演算子の表を差し替えられるようにしたもの
+func newParser(l *lexer.Lexer, operators []Operator) *Parser {
 	p := &Parser{
 		l:      l,
-		errors: []string{},
+		errors: ErrorList{},
 	}
 	// pの curToken の位置を動かすためにポインタで用意
 
 	p.prefixParseFn = make(map[token.TokenType]prefixParseFn)
-	p.registerPrefix(token.IDENT, p.parseIdentifier)       // 変数名の関数をセットする
-	p.registerPrefix(token.INT, p.parseIntegerLiteral)     // intの関数をセットする
-	p.registerPrefix(token.BANG, p.parsePrefixExpression)  // !の関数をセットする
-	p.registerPrefix(token.MINUS, p.parsePrefixExpression) // -の関数をセットする
+	p.registerPrefix(token.IDENT, p.parseIdentifier)   // 変数名の関数をセットする
+	p.registerPrefix(token.INT, p.parseIntegerLiteral) // intの関数をセットする
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
+	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.ILLEGAL, p.parseIllegal)
+	p.registerPrefix(token.TRUE, p.parseBoolean)
+	p.registerPrefix(token.FALSE, p.parseBoolean)
+	p.registerPrefix(token.LPAREN, p.parseGroupedExpression) // ( で始まる式
+	p.registerPrefix(token.IF, p.parseIfExpression)
+	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
+	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
 
 	p.infixParseFn = make(map[token.TokenType]infixParseFn)
-	p.registerInfix(token.EQ, p.parseInfixExpression)
-	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
-	p.registerInfix(token.LT, p.parseInfixExpression)
-	p.registerInfix(token.GT, p.parseInfixExpression)
-	p.registerInfix(token.PLUS, p.parseInfixExpression)
-	p.registerInfix(token.MINUS, p.parseInfixExpression)
-	p.registerInfix(token.SLASH, p.parseInfixExpression)
-	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
+
+	// 演算子は表から登録する
+	p.precedences = make(map[token.TokenType]int)
+	p.prefixPrecedences = make(map[token.TokenType]int)
+	p.rightAssoc = make(map[token.TokenType]bool)
+	p.registerOperators(operators)
 
 	// 2つトークンを読み込む
 	// curToken と peekToken を読み込んでいる
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"fmt"
 	"monkey/ast"
 	"monkey/lexer"
 	"strconv"
@@ -447,15 +448,68 @@ func TestParsingInfixExpressionsMultipleInput2(t *testing.T) {
 	}
 }
 
+// ast の JSON の往復のテストでも使う
+var operatorPrecedenceTests = []struct {
+	input    string
+	expected string
+}{
+	{"-a*b", "((-a) * b)"},
+	{"-a", "(-a)"},
+	{"-3278*vd+89*dv", "(((-3278) * vd) + (89 * dv))"},
+	{"5<4!=3>4", "((5 < 4) != (3 > 4))"},
+	{"a / b * c", "((a / b) * c)"},
+	{"a * b / c", "((a * b) / c)"},
+	{"a + b * c + d / e - f", "(((a + (b * c)) + (d / e)) - f)"},
+	{"a || b && c", "(a || (b && c))"},
+	{"a && b || c && d", "((a && b) || (c && d))"},
+	{"a <= b == c >= d", "((a <= b) == (c >= d))"},
+	{"!a && b == c", "((!a) && (b == c))"},
+	{"a + b % c", "(a + (b % c))"},
+	{"true", "true"},
+	{"false", "false"},
+	{"3 > 5 == false", "((3 > 5) == false)"},
+	{"3 < 5 == true", "((3 < 5) == true)"},
+	{"true == !false", "(true == (!false))"},
+	{"1 + (2 + 3) + 4", "((1 + (2 + 3)) + 4)"},
+	{"(5 + 5) * 2", "((5 + 5) * 2)"},
+	{"2 / (5 + 5)", "(2 / (5 + 5))"},
+	{"-(5 + 5)", "(-(5 + 5))"},
+	{"!(true == true)", "(!(true == true))"},
+	{"((1 + 2))", "(1 + 2)"},
+	{"a + add(b * c) + d", "((a + add((b * c))) + d)"},
+	{"add(a, b, 1, 2 * 3, 4 + 5, add(6, 7 * 8))", "add(a, b, 1, (2 * 3), (4 + 5), add(6, (7 * 8)))"},
+	{"add(a + b + c * d / f + g)", "add((((a + b) + ((c * d) / f)) + g))"},
+	{"-add(x)", "(-add(x))"},
+	{"fn(x) { x }(5)", "fn(x) { x }(5)"},
+	{"a * [1, 2, 3, 4][b * c] * d", "((a * ([1, 2, 3, 4][(b * c)])) * d)"},
+	{"add(a * b[2], b[1], 2 * [1, 2][1])", "add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))"},
+	{"-a[0]", "(-(a[0]))"},
+	{"f(x)[0]", "(f(x)[0])"},
+}
+
 func TestOperatorPrecedenceParsing(t *testing.T) {
+	for _, tt := range operatorPrecedenceTests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		actual_string := program.String()
+		if actual_string != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestLetStatementValues(t *testing.T) {
 	tests := []struct {
-		input    string
-		expected string
+		input              string
+		expectedIdentifier string
+		expectedValue      interface{}
 	}{
-		{"-a*b", "((-a) * b)"},
-		{"-a", "(-a)"},
-		{"-3278*vd+89*dv", "(((-3278) * vd) + (89 * dv))"},
-		{"5<4!=3>4", "((5 < 4) != (3 > 4))"},
+		{"let x = 5;", "x", 5},
+		{"let y = foobar;", "y", "foobar"},
+		{"let z = 10", "z", 10}, // 最後のセミコロンは省略可能
 	}
 
 	for _, tt := range tests {
@@ -464,9 +518,942 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 		program := p.ParseProgram()
 		checkParserErrors(t, p)
 
-		actual_string := program.String()
-		if actual_string != tt.expected {
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+		}
+
+		stmt := program.Statements[0]
+		if !testLetStatement(t, stmt, tt.expectedIdentifier) {
+			return
+		}
+
+		val := stmt.(*ast.LetStatement).Value
+		if !testLiteralExpression(t, val, tt.expectedValue) {
+			return
+		}
+	}
+}
+
+func TestReturnStatementValues(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedValue interface{}
+	}{
+		{"return 5;", 5},
+		{"return foobar;", "foobar"},
+		{"return 10", 10}, // 最後のセミコロンは省略可能
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+		}
+
+		returnStmt, ok := program.Statements[0].(*ast.ReturnStatement)
+		if !ok {
+			t.Fatalf("stmt not *ast.ReturnStatement. got=%T", program.Statements[0])
+		}
+		if !testLiteralExpression(t, returnStmt.ReturnValue, tt.expectedValue) {
+			return
+		}
+	}
+}
+
+func TestLetAndReturnStatementString(t *testing.T) {
+	input := `let x = 1 + 2 * y
+	return -x`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	expected := "let x = (1 + (2 * y));return (-x);"
+	if program.String() != expected {
+		t.Errorf("expected=%q, got=%q", expected, program.String())
+	}
+}
+
+func testIdentifier(t *testing.T, exp ast.Expression, value string) bool {
+	ident, ok := exp.(*ast.Identifier)
+	if !ok {
+		t.Errorf("exp not *ast.Identifier. got=%T", exp)
+		return false
+	}
+
+	if ident.Value != value {
+		t.Errorf("ident.Value not %s. got=%s", value, ident.Value)
+		return false
+	}
+
+	if ident.TokenLiteral() != value {
+		t.Errorf("ident.TokenLiteral not %s. got=%s", value, ident.TokenLiteral())
+		return false
+	}
+
+	return true
+}
+
+// 期待値の型を見て整数か変数名かを判定する
+func testLiteralExpression(t *testing.T, exp ast.Expression, expected interface{}) bool {
+	switch v := expected.(type) {
+	case int:
+		return testIntegerLiteral(t, exp, int64(v))
+	case int64:
+		return testIntegerLiteral(t, exp, v)
+	case string:
+		return testIdentifier(t, exp, v)
+	case bool:
+		return testBooleanLiteral(t, exp, v)
+	}
+	t.Errorf("type of exp not handled. got=%T", exp)
+	return false
+}
+
+func TestNodePositions(t *testing.T) {
+	input := "let x = 1 + 2;\nreturn -abc;"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	tests := []struct {
+		node        ast.Node
+		expectedPos string
+		expectedEnd string
+	}{
+		{program, "1:1", "2:12"},
+		{program.Statements[0], "1:1", "1:14"},
+		{program.Statements[0].(*ast.LetStatement).Value, "1:9", "1:14"},
+		{program.Statements[1], "2:1", "2:12"},
+		{program.Statements[1].(*ast.ReturnStatement).ReturnValue, "2:8", "2:12"},
+	}
+
+	for i, tt := range tests {
+		if tt.node.Pos().String() != tt.expectedPos {
+			t.Errorf("tests[%d] - pos wrong. expected=%s, got=%s", i, tt.expectedPos, tt.node.Pos())
+		}
+		if tt.node.End().String() != tt.expectedEnd {
+			t.Errorf("tests[%d] - end wrong. expected=%s, got=%s", i, tt.expectedEnd, tt.node.End())
+		}
+	}
+}
+
+// ast の JSON の往復のテストでも使う
+var errorRecoveryTests = []struct {
+	input          string
+	expectedErrors int
+	expected       []string // 各文の String()
+}{
+	{
+		"let x 5; let y = 10; return y;",
+		1,
+		[]string{"<bad statement>", "let y = 10;", "return y;"},
+	},
+	{
+		"1 + * 2; let a = 1;",
+		1,
+		[]string{"<bad statement>", "let a = 1;"},
+	},
+	{
+		"let = 10; foo + ; let z = 1;",
+		2,
+		[]string{"<bad statement>", "<bad statement>", "let z = 1;"},
+	},
+	{
+		"let a = ) ) ); return a",
+		1,
+		[]string{"<bad statement>", "return a;"},
+	},
+	{
+		"let a = 1 let b = 2",
+		0,
+		[]string{"let a = 1;", "let b = 2;"},
+	},
+}
+
+func TestErrorRecovery(t *testing.T) {
+	for _, tt := range errorRecoveryTests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != tt.expectedErrors {
+			t.Errorf("input %q: expected %d errors, got %d: %v", tt.input, tt.expectedErrors, len(p.Errors()), p.Errors())
+		}
+
+		if len(program.Statements) != len(tt.expected) {
+			t.Fatalf("input %q: expected %d statements, got %d: %q", tt.input, len(tt.expected), len(program.Statements), program.String())
+		}
+
+		for i, stmt := range program.Statements {
+			if stmt.String() != tt.expected[i] {
+				t.Errorf("input %q: statements[%d] expected=%q, got=%q", tt.input, i, tt.expected[i], stmt.String())
+			}
+		}
+	}
+}
+
+func TestBadStatementRange(t *testing.T) {
+	p := New(lexer.New("let x 5;\nlet y = 1;"))
+	program := p.ParseProgram()
+
+	bad, ok := program.Statements[0].(*ast.BadStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.BadStatement. got=%T", program.Statements[0])
+	}
+
+	if bad.Pos().String() != "1:1" || bad.End().String() != "1:9" {
+		t.Errorf("bad statement range wrong. got=%s-%s", bad.Pos(), bad.End())
+	}
+}
+
+func testBooleanLiteral(t *testing.T, exp ast.Expression, value bool) bool {
+	bo, ok := exp.(*ast.Boolean)
+	if !ok {
+		t.Errorf("exp not *ast.Boolean. got=%T", exp)
+		return false
+	}
+
+	if bo.Value != value {
+		t.Errorf("bo.Value not %t. got=%t", value, bo.Value)
+		return false
+	}
+
+	if bo.TokenLiteral() != fmt.Sprintf("%t", value) {
+		t.Errorf("bo.TokenLiteral not %t. got=%s", value, bo.TokenLiteral())
+		return false
+	}
+
+	return true
+}
+
+func TestBooleanExpression(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedBoolean bool
+	}{
+		{"true;", true},
+		{"false;", false},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has not enough statements. got=%d", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+		}
+
+		testLiteralExpression(t, stmt.Expression, tt.expectedBoolean)
+	}
+}
+
+func TestGroupedExpressionMissingParen(t *testing.T) {
+	p := New(lexer.New("(1 + 2; let a = 1;"))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 1 {
+		t.Fatalf("expected 1 error, got %d: %v", len(p.Errors()), p.Errors())
+	}
+	if p.Errors()[0].Fix == nil || p.Errors()[0].Fix.NewText != ")" {
+		t.Errorf("expected fix inserting ')', got %+v", p.Errors()[0].Fix)
+	}
+	if len(program.Statements) != 2 {
+		t.Fatalf("expected 2 statements, got %d", len(program.Statements))
+	}
+}
+
+func TestIfExpression(t *testing.T) {
+	input := `if (x < y) { x }`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+
+	exp, ok := stmt.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.IfExpression. got=%T", stmt.Expression)
+	}
+
+	if !testInfixExpression(t, exp.Condition, "x", "<", "y") {
+		return
+	}
+
+	if len(exp.Consequence.Statements) != 1 {
+		t.Errorf("consequence is not 1 statements. got=%d", len(exp.Consequence.Statements))
+	}
+
+	consequence, ok := exp.Consequence.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T", exp.Consequence.Statements[0])
+	}
+
+	if !testIdentifier(t, consequence.Expression, "x") {
+		return
+	}
+
+	if exp.Alternative != nil {
+		t.Errorf("exp.Alternative was not nil. got=%+v", exp.Alternative)
+	}
+}
+
+func TestIfElseExpression(t *testing.T) {
+	input := `if (x < y) { x } else { y }`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.IfExpression. got=%T", stmt.Expression)
+	}
+
+	if !testInfixExpression(t, exp.Condition, "x", "<", "y") {
+		return
+	}
+
+	consequence := exp.Consequence.Statements[0].(*ast.ExpressionStatement)
+	if !testIdentifier(t, consequence.Expression, "x") {
+		return
+	}
+
+	if exp.Alternative == nil || len(exp.Alternative.Statements) != 1 {
+		t.Fatalf("exp.Alternative is not 1 statements. got=%+v", exp.Alternative)
+	}
+
+	alternative := exp.Alternative.Statements[0].(*ast.ExpressionStatement)
+	if !testIdentifier(t, alternative.Expression, "y") {
+		return
+	}
+
+	expected := "if ((x < y)) { x } else { y }"
+	if program.String() != expected {
+		t.Errorf("program.String() wrong. expected=%q, got=%q", expected, program.String())
+	}
+}
+
+func TestFunctionLiteralParsing(t *testing.T) {
+	input := `fn(x, y) { x + y; }`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+	}
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	function, ok := stmt.Expression.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.FunctionLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(function.Parameters) != 2 {
+		t.Fatalf("function literal parameters wrong. want 2, got=%d", len(function.Parameters))
+	}
+
+	testLiteralExpression(t, function.Parameters[0], "x")
+	testLiteralExpression(t, function.Parameters[1], "y")
+
+	if len(function.Body.Statements) != 1 {
+		t.Fatalf("function.Body.Statements has not 1 statements. got=%d", len(function.Body.Statements))
+	}
+
+	bodyStmt, ok := function.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("function body stmt is not ast.ExpressionStatement. got=%T", function.Body.Statements[0])
+	}
+
+	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
+
+	if function.End().String() != "1:20" {
+		t.Errorf("function.End() wrong. got=%s", function.End())
+	}
+}
+
+func TestFunctionParameterParsing(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedParams []string
+	}{
+		{input: "fn() {};", expectedParams: []string{}},
+		{input: "fn(x) {};", expectedParams: []string{"x"}},
+		{input: "fn(x, y, z) {};", expectedParams: []string{"x", "y", "z"}},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		function := stmt.Expression.(*ast.FunctionLiteral)
+
+		if len(function.Parameters) != len(tt.expectedParams) {
+			t.Errorf("length parameters wrong. want %d, got=%d", len(tt.expectedParams), len(function.Parameters))
+		}
+
+		for i, ident := range tt.expectedParams {
+			testLiteralExpression(t, function.Parameters[i], ident)
+		}
+	}
+}
+
+func TestCallExpressionParsing(t *testing.T) {
+	input := "add(1, 2 * 3, 4 + 5);"
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+	}
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T", stmt.Expression)
+	}
+
+	if !testIdentifier(t, exp.Function, "add") {
+		return
+	}
+
+	if len(exp.Arguments) != 3 {
+		t.Fatalf("wrong length of arguments. got=%d", len(exp.Arguments))
+	}
+
+	testLiteralExpression(t, exp.Arguments[0], 1)
+	testInfixExpression(t, exp.Arguments[1], 2, "*", 3)
+	testInfixExpression(t, exp.Arguments[2], 4, "+", 5)
+
+	if exp.Pos().String() != "1:1" || exp.End().String() != "1:21" {
+		t.Errorf("call expression range wrong. got=%s-%s", exp.Pos(), exp.End())
+	}
+}
+
+func TestBlockStatementErrorRecovery(t *testing.T) {
+	input := `let f = fn(x) { let = 1; x * 2 }; let g = 1;`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 1 {
+		t.Fatalf("expected 1 error, got %d: %v", len(p.Errors()), p.Errors())
+	}
+
+	expected := "let f = fn(x) { <bad statement> (x * 2) };let g = 1;"
+	if program.String() != expected {
+		t.Errorf("program.String() wrong. expected=%q, got=%q", expected, program.String())
+	}
+}
+
+func testInfixExpression(t *testing.T, exp ast.Expression, left interface{}, operator string, right interface{}) bool {
+	opExp, ok := exp.(*ast.InfixExpression)
+	if !ok {
+		t.Errorf("exp is not ast.InfixExpression. got=%T(%s)", exp, exp)
+		return false
+	}
+
+	if !testLiteralExpression(t, opExp.Left, left) {
+		return false
+	}
+
+	if opExp.Operator != operator {
+		t.Errorf("exp.Operator is not '%s'. got=%q", operator, opExp.Operator)
+		return false
+	}
+
+	if !testLiteralExpression(t, opExp.Right, right) {
+		return false
+	}
+
+	return true
+}
+
+func TestStringLiteralExpression(t *testing.T) {
+	input := `"hello\tworld";`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.StringLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.StringLiteral. got=%T", stmt.Expression)
+	}
+
+	if literal.Value != "hello\tworld" {
+		t.Errorf("literal.Value not %q. got=%q", "hello\tworld", literal.Value)
+	}
+
+	// String() はそのままソースに書ける形に戻す
+	if literal.String() != `"hello\tworld"` {
+		t.Errorf("literal.String() not %q. got=%q", `"hello\tworld"`, literal.String())
+	}
+}
+
+func TestLexerErrorsAsDiagnostics(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedPos    string
+		expectedString string
+	}{
+		{"let a = 1; let s = \"abc", "1:20", "let a = 1;<bad statement>"},
+		{"let a = 1 @ 2; let b = 2;", "1:11", "let a = 1;<bad statement>let b = 2;"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("input %q: expected 1 error, got %d: %v", tt.input, len(errors), errors)
+		}
+		if errors[0].Code != CodeLexError {
+			t.Errorf("input %q: code wrong. got=%s", tt.input, errors[0].Code)
+		}
+		if errors[0].Pos.String() != tt.expectedPos {
+			t.Errorf("input %q: pos wrong. expected=%s, got=%s", tt.input, tt.expectedPos, errors[0].Pos)
+		}
+		if program.String() != tt.expectedString {
+			t.Errorf("input %q: program wrong. expected=%q, got=%q", tt.input, tt.expectedString, program.String())
+		}
+	}
+}
+
+func TestParsingArrayLiterals(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3]"
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	array, ok := stmt.Expression.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf("exp not ast.ArrayLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(array.Elements) != 3 {
+		t.Fatalf("len(array.Elements) not 3. got=%d", len(array.Elements))
+	}
+
+	testIntegerLiteral(t, array.Elements[0], 1)
+	testInfixExpression(t, array.Elements[1], 2, "*", 2)
+	testInfixExpression(t, array.Elements[2], 3, "+", 3)
+
+	if array.End().String() != "1:18" {
+		t.Errorf("array.End() wrong. got=%s", array.End())
+	}
+}
+
+func TestParsingIndexExpressions(t *testing.T) {
+	input := "myArray[1 + 1]"
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	indexExp, ok := stmt.Expression.(*ast.IndexExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.IndexExpression. got=%T", stmt.Expression)
+	}
+
+	if !testIdentifier(t, indexExp.Left, "myArray") {
+		return
+	}
+
+	if !testInfixExpression(t, indexExp.Index, 1, "+", 1) {
+		return
+	}
+}
+
+func TestParsingHashLiterals(t *testing.T) {
+	input := `{"one": 1, "two": 2, "three": 3}`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+	}
+
+	expected := []struct {
+		key   string
+		value int64
+	}{
+		{"one", 1},
+		{"two", 2},
+		{"three", 3},
+	}
+
+	if len(hash.Pairs) != len(expected) {
+		t.Fatalf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
+	}
+
+	// 書かれた順に並んでいる
+	for i, pair := range hash.Pairs {
+		literal, ok := pair.Key.(*ast.StringLiteral)
+		if !ok {
+			t.Errorf("key is not ast.StringLiteral. got=%T", pair.Key)
+			continue
+		}
+
+		if literal.Value != expected[i].key {
+			t.Errorf("key wrong. expected=%q, got=%q", expected[i].key, literal.Value)
+		}
+
+		testIntegerLiteral(t, pair.Value, expected[i].value)
+	}
+}
+
+func TestParsingEmptyHashLiteral(t *testing.T) {
+	p := New(lexer.New("{}"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(hash.Pairs) != 0 {
+		t.Errorf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
+	}
+}
+
+func TestParsingHashLiteralsWithExpressions(t *testing.T) {
+	input := `{"one": 0 + 1, "two": 10 - 8, "three": 15 / 5}`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(hash.Pairs) != 3 {
+		t.Fatalf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
+	}
+
+	testInfixExpression(t, hash.Pairs[0].Value, 0, "+", 1)
+	testInfixExpression(t, hash.Pairs[1].Value, 10, "-", 8)
+	testInfixExpression(t, hash.Pairs[2].Value, 15, "/", 5)
+}
+
+// String() の出力をもう一度パースしても同じ String() になる
+func TestCollectionStringRoundTrip(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[]", "[]"},
+		{"[1,2 ,3]", "[1, 2, 3]"},
+		{"{}", "{}"},
+		{`{"a":1, true: [2], 3: {"b": "c"}}`, `{"a": 1, true: [2], 3: {"b": "c"}}`},
+		{"a[1][2]", "((a[1])[2])"},
+		{`let h = {"k": [1, 2 + 3]}["k"][1]`, `let h = (({"k": [1, (2 + 3)]}["k"])[1]);`},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+
+		p = New(lexer.New(program.String()))
+		again := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if again.String() != tt.expected {
+			t.Errorf("round trip wrong. expected=%q, got=%q", tt.expected, again.String())
+		}
+	}
+}
+
+func TestParsingWithComments(t *testing.T) {
+	input := `// 足し算
+	let add = fn(a, b) { /* 足す */ a + b }; // 定義
+	add(1, 2) // 呼び出し`
+
+	expected := "let add = fn(a, b) { (a + b) };add(1, 2)"
+
+	for _, mode := range []lexer.CommentMode{lexer.SkipComments, lexer.EmitComments, lexer.AttachComments} {
+		p := New(lexer.New(input, lexer.WithComments(mode)))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != expected {
+			t.Errorf("mode %d: expected=%q, got=%q", mode, expected, program.String())
+		}
+	}
+}
+
+func TestUnicodeSource(t *testing.T) {
+	input := "let 合計 = 値 + 1;\n合計 * x"
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	expected := "let 合計 = (値 + 1);(合計 * x)"
+	if program.String() != expected {
+		t.Errorf("expected=%q, got=%q", expected, program.String())
+	}
+
+	// 列は文字単位、オフセットはバイト単位
+	value := program.Statements[0].(*ast.LetStatement).Value
+	if value.Pos().Column != 10 || value.Pos().Offset != 13 {
+		t.Errorf("value pos wrong. got=%+v", value.Pos())
+	}
+}
+
+func TestInvalidUTF8Diagnostic(t *testing.T) {
+	input := "let 値 = \xff;"
+
+	p := New(lexer.New(input))
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("expected 1 error, got %d: %v", len(errors), errors)
+	}
+	if errors[0].Code != CodeLexError || errors[0].Pos.String() != "1:9" {
+		t.Errorf("diagnostic wrong. got=%+v", errors[0])
+	}
+
+	// 値 は全角なので2マス分ずらす
+	expected := "let 値 = \xff;\n         ^"
+	if errors[0].Excerpt(input) != expected {
+		t.Errorf("excerpt wrong. expected=%q, got=%q", expected, errors[0].Excerpt(input))
+	}
+}
+
+func TestNumberLiteralParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"0x1F", int64(31)},
+		{"0o17", int64(15)},
+		{"0b1010", int64(10)},
+		{"1_000_000", int64(1000000)},
+		{"9223372036854775807", int64(9223372036854775807)},
+		{"3.14", 3.14},
+		{"1e-9", 1e-9},
+		{"1_000.5", 1000.5},
+		{"2E3", 2000.0},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+
+		switch expected := tt.expected.(type) {
+		case int64:
+			literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+			if !ok {
+				t.Fatalf("input %q: exp not *ast.IntegerLiteral. got=%T", tt.input, stmt.Expression)
+			}
+			if literal.Value != expected {
+				t.Errorf("input %q: value wrong. expected=%d, got=%d", tt.input, expected, literal.Value)
+			}
+		case float64:
+			literal, ok := stmt.Expression.(*ast.FloatLiteral)
+			if !ok {
+				t.Fatalf("input %q: exp not *ast.FloatLiteral. got=%T", tt.input, stmt.Expression)
+			}
+			if literal.Value != expected {
+				t.Errorf("input %q: value wrong. expected=%g, got=%g", tt.input, expected, literal.Value)
+			}
+		}
+	}
+}
+
+func TestFloatLiteralString(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"3.14", "3.14"},
+		{"3.0", "3.0"},
+		{"1e-9", "1e-09"},
+		{"-2.5 * 2", "((-2.5) * 2)"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
 			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
 		}
 	}
 }
+
+func TestNumberOutOfRange(t *testing.T) {
+	p := New(lexer.New("let big = 1 + 99999999999999999999;"))
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("expected 1 error, got %d: %v", len(errors), errors)
+	}
+
+	expected := "1:15: integer literal 99999999999999999999 out of range"
+	if errors[0].Error() != expected {
+		t.Errorf("error wrong. expected=%q, got=%q", expected, errors[0].Error())
+	}
+}
+
+func TestLogicalExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		left     interface{}
+		operator string
+		right    interface{}
+	}{
+		{"a && b", "a", "&&", "b"},
+		{"true || false", true, "||", false},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		exp, ok := stmt.Expression.(*ast.LogicalExpression)
+		if !ok {
+			t.Fatalf("exp is not *ast.LogicalExpression. got=%T", stmt.Expression)
+		}
+
+		testLiteralExpression(t, exp.Left, tt.left)
+		if exp.Operator != tt.operator {
+			t.Errorf("exp.Operator is not '%s'. got=%s", tt.operator, exp.Operator)
+		}
+		testLiteralExpression(t, exp.Right, tt.right)
+	}
+}
+
+func TestASTJSONRoundTrip(t *testing.T) {
+	inputs := []string{
+		"let x = 5; let y = true; let foobar = y;",
+		"return 5; return 10; return add(15);",
+		"if (x < y) { x }",
+		"if (x < y) { x } else { y }",
+		"fn(x, y) { x + y; }",
+		"fn() {};",
+		"\"hello\\n\\u{1F600} world\"",
+		"[1, 2 * 2, 3 + 3]",
+		"myArray[1 + 1]",
+		`{"one": 1, "two": 2, "three": 3}`,
+		`{"one": 0 + 1, true: 10 - 8}`,
+		"{}",
+		"0x1F; 1_000; 3.14; 1e10; 2.5e-3",
+		"// comment\nlet x = 1; /* block */ x",
+		"let 名前 = \"値\";",
+		"a && (b || !c)",
+		"let x = {1: 2; let y = 3;",
+	}
+	for _, tt := range operatorPrecedenceTests {
+		inputs = append(inputs, tt.input)
+	}
+	for _, tt := range errorRecoveryTests {
+		inputs = append(inputs, tt.input)
+	}
+
+	for _, input := range inputs {
+		program := New(lexer.New(input, lexer.WithFilename("test.mk"))).ParseProgram()
+
+		data, err := ast.MarshalJSON(program)
+		if err != nil {
+			t.Fatalf("input %q: MarshalJSON failed: %s", input, err)
+		}
+
+		node, err := ast.UnmarshalJSON(data)
+		if err != nil {
+			t.Fatalf("input %q: UnmarshalJSON failed: %s\n%s", input, err, data)
+		}
+
+		if node.String() != program.String() {
+			t.Errorf("input %q: String() changed.\nexpected=%q\ngot=%q", input, program.String(), node.String())
+		}
+
+		// トークンや位置も含めて同じ JSON に戻ること
+		again, err := ast.MarshalJSON(node)
+		if err != nil {
+			t.Fatalf("input %q: MarshalJSON failed: %s", input, err)
+		}
+		if string(again) != string(data) {
+			t.Errorf("input %q: JSON changed.\nexpected=%s\ngot=%s", input, data, again)
+		}
+	}
+}
+
+func TestUnterminatedHashLiteral(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"{1:2", "expected next token to be }, got EOF instead"},
+		{"{1:2,", "no prefix parse function for EOF found"},
+		{"{1:2, 3", "expected next token to be :, got EOF instead"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("input %q: expected 1 error, got %d: %v", tt.input, len(errors), errors)
+		}
+		if errors[0].Message != tt.expected {
+			t.Errorf("input %q: message wrong. expected=%q, got=%q", tt.input, tt.expected, errors[0].Message)
+		}
+	}
+}
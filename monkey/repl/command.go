@@ -0,0 +1,123 @@
+package repl
+
+import (
+	"fmt"
+	"monkey/lexer"
+	"monkey/object"
+	"os"
+	"strings"
+)
+
+// : で始まるREPL自体への指示
+type command struct {
+	name  string
+	args  string // :help に出す引数の説明
+	help  string
+	nargs int // 引数の数 (-1 なら0個か1個)
+	run   func(r *repl, args []string)
+}
+
+// :help にはこの順で並べる
+// 初期化の循環を避けるため init で作る
+var commands []command
+
+func init() {
+	commands = []command{
+		{":help", "", "show this help", 0, (*repl).help},
+		{":mode", "[eval|tokens|ast|sexpr]", "show or change the mode", -1, (*repl).setMode},
+		{":env", "", "list the bindings in the environment", 0, (*repl).listEnv},
+		{":load", "<file>", "evaluate a file in the current environment", 1, (*repl).load},
+		{":save", "<file>", "write the statements evaluated so far to a file", 1, (*repl).save},
+		{":reset", "", "clear the environment and the saved statements", 0, (*repl).reset},
+	}
+}
+
+// :mode のようなコマンドを実行する
+func (r *repl) command(line string) {
+	fields := strings.Fields(line)
+	name, args := fields[0], fields[1:]
+
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+		if (c.nargs >= 0 && len(args) != c.nargs) || (c.nargs < 0 && len(args) > 1) {
+			fmt.Fprintf(r.out, "usage: %s %s\n", c.name, c.args)
+			return
+		}
+		c.run(r, args)
+		return
+	}
+
+	fmt.Fprintf(r.out, "unknown command %s (type :help for a list)\n", name)
+}
+
+func (r *repl) help(args []string) {
+	for _, c := range commands {
+		fmt.Fprintf(r.out, "%-8s%-26s%s\n", c.name, c.args, c.help)
+	}
+}
+
+func (r *repl) setMode(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintf(r.out, "mode: %s\n", r.mode)
+		return
+	}
+	mode, err := ParseMode(args[0])
+	if err != nil {
+		fmt.Fprintln(r.out, err)
+		return
+	}
+	r.mode = mode
+	fmt.Fprintf(r.out, "mode: %s\n", r.mode)
+}
+
+func (r *repl) listEnv(args []string) {
+	for _, name := range r.env.Names() {
+		value, _ := r.env.Get(name)
+		if value == nil {
+			fmt.Fprintf(r.out, "%s = null\n", name)
+			continue
+		}
+		fmt.Fprintf(r.out, "%s = %s\n", name, value.Inspect())
+	}
+}
+
+// ファイルを今の環境で評価する
+// モードによらず評価するので、関数定義などを読み込んでから試せる
+func (r *repl) load(args []string) {
+	filename := args[0]
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintln(r.out, err)
+		return
+	}
+
+	mode := r.mode
+	r.mode = ModeEval
+	defer func() { r.mode = mode }()
+
+	if r.run(string(src), lexer.New(string(src), lexer.WithFilename(filename))) {
+		r.accepted = append(r.accepted, strings.TrimRight(string(src), "\n"))
+	}
+}
+
+func (r *repl) save(args []string) {
+	var out strings.Builder
+	for _, src := range r.accepted {
+		out.WriteString(src)
+		out.WriteString("\n")
+	}
+
+	if err := os.WriteFile(args[0], []byte(out.String()), 0o644); err != nil {
+		fmt.Fprintln(r.out, err)
+		return
+	}
+	fmt.Fprintf(r.out, "saved %d statements to %s\n", len(r.accepted), args[0])
+}
+
+func (r *repl) reset(args []string) {
+	r.env = object.NewEnvironment()
+	r.accepted = nil
+	fmt.Fprintln(r.out, "environment reset")
+}
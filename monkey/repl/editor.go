@@ -0,0 +1,369 @@
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"monkey/width"
+	"strings"
+	"unicode"
+)
+
+// 1行読むもの
+// 端末なら行エディタ、パイプやテストでは bufio.Scanner で読む
+type lineReader interface {
+	readLine(prompt string) (string, error)
+}
+
+// 端末ではないときに使う、1行ずつ読むだけのもの
+type scanReader struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+func (s *scanReader) readLine(prompt string) (string, error) {
+	io.WriteString(s.out, prompt)
+	if !s.scanner.Scan() {
+		if err := s.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return s.scanner.Text(), nil
+}
+
+// 押されたキー
+// 普通の文字はそのまま、矢印キーなどのエスケープシーケンスは負の値にする
+type key rune
+
+const (
+	keyUnknown key = -(iota + 1)
+	keyUp
+	keyDown
+	keyRight
+	keyLeft
+	keyHome
+	keyEnd
+	keyDelete
+)
+
+const (
+	keyCtrlA     key = 1
+	keyCtrlB     key = 2
+	keyCtrlC     key = 3
+	keyCtrlD     key = 4
+	keyCtrlE     key = 5
+	keyCtrlF     key = 6
+	keyCtrlH     key = 8
+	keyTab       key = 9
+	keyLineFeed  key = 10
+	keyCtrlK     key = 11
+	keyCtrlL     key = 12
+	keyEnter     key = 13
+	keyCtrlN     key = 14
+	keyCtrlP     key = 16
+	keyCtrlU     key = 21
+	keyCtrlW     key = 23
+	keyEscape    key = 27
+	keyBackspace key = 127
+)
+
+// 端末用の行エディタ
+// 1文字ずつ読んで、そのたびに行を書き直す
+type editor struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	raw       func() (restore func(), err error) // 読んでいる間だけ端末を raw モードにする (nil なら何もしない)
+	history   []string                           // 古い順
+	complete  func(word string) []string         // Tab で補完する候補
+	highlight func(line string) string           // 行に色を付ける (nil なら付けない)
+
+	// 編集中の行
+	prompt  string
+	buf     []rune
+	pos     int    // カーソルの位置 (buf の添字)
+	histPos int    // 表示している履歴 (len(history) なら編集中の行)
+	draft   []rune // 履歴を遡る前に編集していた行
+}
+
+func newEditor(in io.Reader, out io.Writer) *editor {
+	return &editor{in: bufio.NewReader(in), out: out}
+}
+
+func (e *editor) readLine(prompt string) (string, error) {
+	if e.raw != nil {
+		restore, err := e.raw()
+		if err != nil {
+			return "", err
+		}
+		defer restore()
+	}
+
+	e.prompt = prompt
+	e.buf = nil
+	e.pos = 0
+	e.histPos = len(e.history)
+	e.draft = nil
+	e.refresh()
+
+	for {
+		k, err := e.readKey()
+		if err != nil {
+			// 改行のないまま入力が終わったら、そこまでを1行とする
+			if err == io.EOF && len(e.buf) > 0 {
+				return e.accept(), nil
+			}
+			return "", err
+		}
+
+		switch k {
+		case keyEnter, keyLineFeed:
+			return e.accept(), nil
+		case keyCtrlD:
+			if len(e.buf) == 0 {
+				io.WriteString(e.out, "\r\n")
+				return "", io.EOF
+			}
+			e.deleteChar()
+		case keyCtrlC:
+			// 入力中の行を捨てて、新しい行から
+			io.WriteString(e.out, "^C\r\n")
+			e.buf = nil
+			e.pos = 0
+		case keyBackspace, keyCtrlH:
+			if e.pos > 0 {
+				e.pos--
+				e.deleteChar()
+			}
+		case keyDelete:
+			e.deleteChar()
+		case keyLeft, keyCtrlB:
+			if e.pos > 0 {
+				e.pos--
+			}
+		case keyRight, keyCtrlF:
+			if e.pos < len(e.buf) {
+				e.pos++
+			}
+		case keyHome, keyCtrlA:
+			e.pos = 0
+		case keyEnd, keyCtrlE:
+			e.pos = len(e.buf)
+		case keyCtrlK:
+			e.buf = e.buf[:e.pos]
+		case keyCtrlU:
+			e.buf = e.buf[e.pos:]
+			e.pos = 0
+		case keyCtrlW:
+			// 直前の空白と、その前の空白までを消す
+			start := e.pos
+			for start > 0 && e.buf[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && e.buf[start-1] != ' ' {
+				start--
+			}
+			e.buf = append(e.buf[:start], e.buf[e.pos:]...)
+			e.pos = start
+		case keyUp, keyCtrlP:
+			e.moveHistory(-1)
+		case keyDown, keyCtrlN:
+			e.moveHistory(1)
+		case keyTab:
+			e.completeWord()
+		case keyCtrlL:
+			io.WriteString(e.out, "\x1b[H\x1b[2J")
+		default:
+			if k >= 0 && unicode.IsPrint(rune(k)) {
+				e.insert([]rune{rune(k)})
+			}
+		}
+
+		e.refresh()
+	}
+}
+
+// 入力を確定して行を返す
+func (e *editor) accept() string {
+	e.pos = len(e.buf)
+	e.refresh()
+	io.WriteString(e.out, "\r\n")
+
+	line := string(e.buf)
+	if strings.TrimSpace(line) != "" && (len(e.history) == 0 || e.history[len(e.history)-1] != line) {
+		e.history = append(e.history, line)
+	}
+	return line
+}
+
+// キーを1つ読む
+func (e *editor) readKey() (key, error) {
+	r, _, err := e.in.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	if key(r) != keyEscape {
+		return key(r), nil
+	}
+
+	// ESC [ A や ESC O H のような形
+	b, err := e.in.ReadByte()
+	if err != nil || (b != '[' && b != 'O') {
+		return keyUnknown, nil
+	}
+	b, err = e.in.ReadByte()
+	if err != nil {
+		return keyUnknown, nil
+	}
+
+	switch b {
+	case 'A':
+		return keyUp, nil
+	case 'B':
+		return keyDown, nil
+	case 'C':
+		return keyRight, nil
+	case 'D':
+		return keyLeft, nil
+	case 'H':
+		return keyHome, nil
+	case 'F':
+		return keyEnd, nil
+	}
+
+	// ESC [ 3 ~ のように数字で送ってくる端末もある
+	if b < '0' || b > '9' {
+		return keyUnknown, nil
+	}
+	n := int(b - '0')
+	for {
+		b, err = e.in.ReadByte()
+		if err != nil {
+			return keyUnknown, nil
+		}
+		if b < '0' || b > '9' {
+			break
+		}
+		n = n*10 + int(b-'0')
+	}
+	if b != '~' {
+		return keyUnknown, nil
+	}
+
+	switch n {
+	case 1, 7:
+		return keyHome, nil
+	case 4, 8:
+		return keyEnd, nil
+	case 3:
+		return keyDelete, nil
+	}
+	return keyUnknown, nil
+}
+
+func (e *editor) insert(rs []rune) {
+	buf := make([]rune, 0, len(e.buf)+len(rs))
+	buf = append(buf, e.buf[:e.pos]...)
+	buf = append(buf, rs...)
+	buf = append(buf, e.buf[e.pos:]...)
+	e.buf = buf
+	e.pos += len(rs)
+}
+
+// カーソルの位置の文字を消す
+func (e *editor) deleteChar() {
+	if e.pos < len(e.buf) {
+		e.buf = append(e.buf[:e.pos], e.buf[e.pos+1:]...)
+	}
+}
+
+// 履歴を d だけ進める (負なら古い方へ)
+func (e *editor) moveHistory(d int) {
+	n := e.histPos + d
+	if n < 0 || n > len(e.history) {
+		return
+	}
+
+	if e.histPos == len(e.history) {
+		e.draft = append([]rune(nil), e.buf...)
+	}
+	e.histPos = n
+
+	if n == len(e.history) {
+		e.buf = e.draft
+	} else {
+		e.buf = []rune(e.history[n])
+	}
+	e.pos = len(e.buf)
+}
+
+// カーソルの直前にある単語の始まり
+func (e *editor) wordStart() int {
+	start := e.pos
+	for start > 0 && isWordRune(e.buf[start-1]) {
+		start--
+	}
+	return start
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == ':'
+}
+
+// カーソルの直前の単語を補完する
+// 候補が1つならそれに、複数なら共通の部分まで埋めて、それ以上埋まらなければ候補を並べる
+func (e *editor) completeWord() {
+	if e.complete == nil {
+		return
+	}
+	word := e.buf[e.wordStart():e.pos]
+	if len(word) == 0 {
+		return
+	}
+
+	candidates := e.complete(string(word))
+	if len(candidates) == 0 {
+		io.WriteString(e.out, "\a")
+		return
+	}
+
+	prefix := []rune(candidates[0])
+	for _, c := range candidates[1:] {
+		prefix = commonPrefix(prefix, []rune(c))
+	}
+
+	if len(prefix) > len(word) {
+		e.insert(prefix[len(word):])
+		return
+	}
+	if len(candidates) > 1 {
+		io.WriteString(e.out, "\r\n"+strings.Join(candidates, "  ")+"\r\n")
+	}
+}
+
+func commonPrefix(a, b []rune) []rune {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return a[:n]
+}
+
+// 行を書き直してカーソルを置き直す
+func (e *editor) refresh() {
+	line := string(e.buf)
+	if e.highlight != nil {
+		line = e.highlight(line)
+	}
+
+	var out strings.Builder
+	out.WriteString("\r")
+	out.WriteString(e.prompt)
+	out.WriteString(line)
+	out.WriteString("\x1b[K") // 行末まで消す
+	if w := width.Runes(e.buf[e.pos:]); w > 0 {
+		fmt.Fprintf(&out, "\x1b[%dD", w)
+	}
+	io.WriteString(e.out, out.String())
+}
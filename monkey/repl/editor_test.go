@@ -0,0 +1,153 @@
+package repl
+
+import (
+	"bytes"
+	"io"
+	"monkey/object"
+	"strings"
+	"testing"
+)
+
+const (
+	up        = "\x1b[A"
+	down      = "\x1b[B"
+	right     = "\x1b[C"
+	left      = "\x1b[D"
+	home      = "\x1b[H"
+	end       = "\x1b[4~"
+	del       = "\x1b[3~"
+	backspace = "\x7f"
+)
+
+func TestEditorKeys(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 1;\r", "let x = 1;"},
+		{"abc" + backspace + "d\n", "abd"},
+		{"ac" + left + "b\r", "abc"},
+		{"bc" + home + "a" + end + "d\r", "abcd"},
+		{"bc\x01a\x05d\r", "abcd"},
+		{"abc" + home + del + "\r", "bc"},
+		{"abc" + left + left + "\x0b\r", "a"},
+		{"abc" + left + "\x15\r", "c"},
+		{"let foo = bar\x17\x17\r", "let foo "},
+		{"ab" + left + left + left + right + right + right + "c\r", "abc"},
+		// 全角文字も1文字として扱う
+		{"名前" + backspace + "札\r", "名札"},
+		// Ctrl-C でそれまでの入力を捨てる
+		{"abc\x03def\r", "def"},
+		// 知らないエスケープシーケンスは無視する
+		{"a\x1b[Zb\r", "ab"},
+	}
+
+	for i, tt := range tests {
+		var out bytes.Buffer
+		e := newEditor(strings.NewReader(tt.input), &out)
+
+		line, err := e.readLine(PROMPT)
+		if err != nil {
+			t.Fatalf("tests[%d] - readLine returned error: %s", i, err)
+		}
+		if line != tt.expected {
+			t.Errorf("tests[%d] - line wrong. expected=%q, got=%q", i, tt.expected, line)
+		}
+	}
+}
+
+func TestEditorEOF(t *testing.T) {
+	e := newEditor(strings.NewReader("abc\r\x04"), &bytes.Buffer{})
+
+	line, err := e.readLine(PROMPT)
+	if err != nil || line != "abc" {
+		t.Fatalf("first line wrong. expected=%q, got=%q (%v)", "abc", line, err)
+	}
+
+	// 空の行で Ctrl-D なら終わり
+	if _, err := e.readLine(PROMPT); err != io.EOF {
+		t.Fatalf("expected io.EOF, got=%v", err)
+	}
+}
+
+func TestEditorHistory(t *testing.T) {
+	input := "second\r" +
+		up + "\r" + // 直前と同じ行は履歴に重ねない
+		"draft" + up + up + up + down + down + down + "\r" +
+		up + up + up + "!\r"
+	e := newEditor(strings.NewReader(input), &bytes.Buffer{})
+	e.history = []string{"first"}
+
+	expected := []string{"second", "second", "draft", "first!"}
+	for i, exp := range expected {
+		line, err := e.readLine(PROMPT)
+		if err != nil {
+			t.Fatalf("lines[%d] - readLine returned error: %s", i, err)
+		}
+		if line != exp {
+			t.Errorf("lines[%d] wrong. expected=%q, got=%q", i, exp, line)
+		}
+	}
+
+	expectedHistory := []string{"first", "second", "draft", "first!"}
+	if strings.Join(e.history, ",") != strings.Join(expectedHistory, ",") {
+		t.Errorf("history wrong. expected=%q, got=%q", expectedHistory, e.history)
+	}
+}
+
+func TestEditorCompletion(t *testing.T) {
+	r := &repl{env: object.NewEnvironment()}
+	r.env.Set("counter", &object.Integer{Value: 1})
+	r.env.Set("count", &object.Integer{Value: 2})
+
+	tests := []struct {
+		input           string
+		expected        string
+		expectedListing bool
+	}{
+		{"le\t x\r", "let x", false},
+		{"ret\t\r", "return", false},
+		{"fa\t\r", "false", false},
+		// 候補が複数なら共通部分まで
+		{"let y = cou\t\r", "let y = count", false},
+		// それ以上埋まらなければ候補を並べる
+		{"count\t\r", "count", true},
+		{"zzz\t\r", "zzz", false},
+		{":lo\t\r", ":load", false},
+	}
+
+	for i, tt := range tests {
+		var out bytes.Buffer
+		e := newEditor(strings.NewReader(tt.input), &out)
+		e.complete = r.complete
+
+		line, err := e.readLine(PROMPT)
+		if err != nil {
+			t.Fatalf("tests[%d] - readLine returned error: %s", i, err)
+		}
+		if line != tt.expected {
+			t.Errorf("tests[%d] - line wrong. expected=%q, got=%q", i, tt.expected, line)
+		}
+
+		listed := strings.Contains(out.String(), "count  counter")
+		if listed != tt.expectedListing {
+			t.Errorf("tests[%d] - listing wrong. expected=%t, got=%t (%q)", i, tt.expectedListing, listed, out.String())
+		}
+	}
+}
+
+func TestEditorRefresh(t *testing.T) {
+	var out bytes.Buffer
+	e := newEditor(strings.NewReader("名前x"+left+left+"\r"), &out)
+	e.highlight = highlight
+
+	if _, err := e.readLine(PROMPT); err != nil {
+		t.Fatal(err)
+	}
+
+	// カーソルを戻すとき、全角文字は2マスぶん動かす
+	expected := "\r>> 名前x\x1b[K\x1b[3D"
+	if !strings.Contains(out.String(), expected) {
+		t.Errorf("refresh output wrong. expected to contain %q, got=%q", expected, out.String())
+	}
+}
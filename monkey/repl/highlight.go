@@ -0,0 +1,61 @@
+package repl
+
+import (
+	"monkey/lexer"
+	"monkey/token"
+	"strings"
+)
+
+// ANSI エスケープシーケンスの色
+const (
+	colorReset   = "\x1b[0m"
+	colorKeyword = "\x1b[35m" // マゼンタ
+	colorString  = "\x1b[32m" // 緑
+	colorNumber  = "\x1b[36m" // シアン
+	colorComment = "\x1b[90m" // 灰色
+	colorIllegal = "\x1b[31m" // 赤
+)
+
+// 字句解析器でトークンに分けて、種類ごとに色を付ける
+// 色を付けない部分 (空白や記号) は元のまま残す
+func highlight(src string) string {
+	var out strings.Builder
+	last := 0
+
+	l := lexer.New(src, lexer.WithComments(lexer.EmitComments))
+	for _, tok := range l.Tokens() {
+		color := tokenColor(tok)
+		if color == "" {
+			continue
+		}
+
+		out.WriteString(src[last:tok.Pos.Offset])
+		out.WriteString(color)
+		out.WriteString(src[tok.Pos.Offset:tok.End.Offset])
+		out.WriteString(colorReset)
+		last = tok.End.Offset
+	}
+	out.WriteString(src[last:])
+
+	return out.String()
+}
+
+func tokenColor(tok token.Token) string {
+	switch tok.Type {
+	case token.STRING:
+		return colorString
+	case token.INT, token.FLOAT:
+		return colorNumber
+	case token.COMMENT:
+		return colorComment
+	case token.ILLEGAL:
+		return colorIllegal
+	case token.IDENT:
+		return ""
+	}
+
+	if token.LookupIdent(tok.Literal) == tok.Type {
+		return colorKeyword
+	}
+	return ""
+}
@@ -0,0 +1,26 @@
+package repl
+
+import "testing"
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 10;", colorKeyword + "let" + colorReset + " x = " + colorNumber + "10" + colorReset + ";"},
+		{`fn(s) { "a" + s }`, colorKeyword + "fn" + colorReset + `(s) { ` + colorString + `"a"` + colorReset + " + s }"},
+		{"true // yes", colorKeyword + "true" + colorReset + " " + colorComment + "// yes" + colorReset},
+		{"1.5 @", colorNumber + "1.5" + colorReset + " " + colorIllegal + "@" + colorReset},
+		// 入力途中の閉じていない文字列も色を付ける
+		{`x = "ab`, `x = ` + colorString + `"ab` + colorReset},
+		{"名前 + 1", "名前 + " + colorNumber + "1" + colorReset},
+		{"", ""},
+	}
+
+	for i, tt := range tests {
+		got := highlight(tt.input)
+		if got != tt.expected {
+			t.Errorf("tests[%d] - highlight wrong.\nexpected=%q\ngot=%q", i, tt.expected, got)
+		}
+	}
+}
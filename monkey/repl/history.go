@@ -0,0 +1,68 @@
+package repl
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// 入力した行をファイルに追記していく
+// main.go からは ~/.monkey_history を渡している
+func WithHistoryFile(path string) Option {
+	return func(r *repl) {
+		r.historyFile = path
+	}
+}
+
+// 行エディタで遡れる履歴の数
+const maxHistory = 1000
+
+// 前回までの履歴を古い順に読む
+func (r *repl) loadHistory() []string {
+	if r.historyFile == "" {
+		return nil
+	}
+
+	content, err := os.ReadFile(r.historyFile)
+	if err != nil {
+		return nil
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > maxHistory {
+		lines = lines[len(lines)-maxHistory:]
+	}
+	return lines
+}
+
+func (r *repl) openHistory() {
+	if r.historyFile == "" {
+		return
+	}
+
+	f, err := os.OpenFile(r.historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		// 履歴が残せなくてもREPLは使えるので、知らせるだけにする
+		fmt.Fprintf(r.out, "history disabled: %s\n", err)
+		return
+	}
+	r.history = f
+}
+
+func (r *repl) closeHistory() {
+	if r.history != nil {
+		r.history.Close()
+	}
+}
+
+func (r *repl) addHistory(line string) {
+	if r.history == nil || line == "" {
+		return
+	}
+	fmt.Fprintln(r.history, line)
+}
@@ -0,0 +1,48 @@
+package repl
+
+import "fmt"
+
+// 入力した行をどう扱うか
+type Mode int
+
+const (
+	ModeEval   Mode = iota // 評価して結果を出す
+	ModeTokens             // 字句解析の結果を出す
+	ModeAST                // 構文解析の結果を ast.Program.String() で出す
+	ModeSexpr              // 構文解析の結果をS式で出す
+)
+
+var modeNames = map[Mode]string{
+	ModeEval:   "eval",
+	ModeTokens: "tokens",
+	ModeAST:    "ast",
+	ModeSexpr:  "sexpr",
+}
+
+func (m Mode) String() string {
+	if name, ok := modeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("Mode(%d)", int(m))
+}
+
+// "tokens" などの名前からモードを得る
+func ParseMode(name string) (Mode, error) {
+	for m, n := range modeNames {
+		if n == name {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown mode %q (want eval, tokens, ast or sexpr)", name)
+}
+
+// Start に渡す設定
+type Option func(*repl)
+
+// 最初のモードを変える
+// 何も指定しなければ ModeEval
+func WithMode(mode Mode) Option {
+	return func(r *repl) {
+		r.mode = mode
+	}
+}
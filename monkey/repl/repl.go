@@ -4,27 +4,210 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"monkey/astdump"
+	"monkey/evaluator"
 	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
 	"monkey/token"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
 )
 
 const PROMPT = ">> "
 
-func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
+// 括弧が閉じていないときに、続きの入力を促すプロンプト
+const CONTINUATION_PROMPT = ".. "
+
+// REPL の状態
+// 環境は行をまたいで残るので、前の行で let した変数を使える
+type repl struct {
+	out  io.Writer
+	mode Mode
+	env  *object.Environment
+
+	accepted    []string // エラーなく評価できたソース (:save で書き出す)
+	historyFile string   // 入力した行を追記するファイル (空なら残さない)
+	history     *os.File
+}
+
+func Start(in io.Reader, out io.Writer, opts ...Option) {
+	r := &repl{out: out, mode: ModeEval, env: object.NewEnvironment()}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.openHistory()
+	defer r.closeHistory()
+	lines := r.newLineReader(in)
+
+	// 括弧が閉じるまでの行をためておく
+	var pending []string
 
 	for {
-		fmt.Print(PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
+		prompt := PROMPT
+		if len(pending) != 0 {
+			prompt = CONTINUATION_PROMPT
+		}
+
+		line, err := lines.readLine(prompt)
+		if err != nil {
+			if err != io.EOF {
+				fmt.Fprintln(out, err)
+			}
+			// 閉じないまま入力が終わったら、そこまでを処理してエラーを見せる
+			if len(pending) != 0 {
+				r.eval(strings.Join(pending, "\n"))
+			}
 			return
 		}
 
-		line := scanner.Text()
-		l := lexer.New(line)
+		r.addHistory(line)
+
+		if len(pending) == 0 {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+
+			// : で始まる行はREPL自体への指示
+			if strings.HasPrefix(line, ":") {
+				r.command(line)
+				continue
+			}
+		}
+
+		pending = append(pending, line)
+		src := strings.Join(pending, "\n")
+		if unclosed(src) {
+			continue
+		}
+
+		pending = nil
+		r.eval(src)
+	}
+}
+
+// 端末からの入力なら true
+// ファイルでないもの (テストで渡す strings.Reader など) は端末ではないとみなす
+// Linux では端末の設定が読めるかで、それ以外ではキャラクタデバイスかどうかで判断する
+func IsTerminal(in io.Reader) bool {
+	f, ok := in.(*os.File)
+	return ok && isTerminal(f)
+}
 
-		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
-			fmt.Printf("%+v\n", tok)
+// キャラクタデバイスなら true
+// 端末の設定を読めない環境で、端末かどうかを判断する代わりに使う
+func isCharDevice(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// 端末で raw モードにできるなら行エディタを、そうでなければ1行ずつ読むだけのものを使う
+func (r *repl) newLineReader(in io.Reader) lineReader {
+	f, ok := in.(*os.File)
+	if !ok || !isTerminal(f) {
+		return &scanReader{scanner: bufio.NewScanner(in), out: r.out}
+	}
+
+	// raw モードに対応していない環境 (Linux 以外) では行エディタは使えない
+	restore, err := makeRaw(f.Fd())
+	if err != nil {
+		return &scanReader{scanner: bufio.NewScanner(in), out: r.out}
+	}
+	restore()
+
+	e := newEditor(in, r.out)
+	e.raw = func() (func(), error) { return makeRaw(f.Fd()) }
+	e.history = r.loadHistory()
+	e.complete = r.complete
+	e.highlight = highlight
+	return e
+}
+
+// Tab で補完する候補
+// : で始まればコマンド、それ以外はキーワードと今の環境で束縛されている名前
+func (r *repl) complete(word string) []string {
+	var words []string
+	if strings.HasPrefix(word, ":") {
+		for _, c := range commands {
+			words = append(words, c.name)
+		}
+	} else {
+		words = append(token.Keywords(), r.env.Names()...)
+	}
+
+	var candidates []string
+	for _, w := range words {
+		if strings.HasPrefix(w, word) {
+			candidates = append(candidates, w)
+		}
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+// { ( [ のどれかが閉じていなければ true
+// 文字列やコメントの中の括弧は数えないように、字句解析器を通して数える
+func unclosed(src string) bool {
+	depth := 0
+	tokens, _ := lexer.Tokenize(src)
+	for _, tok := range tokens {
+		switch tok.Type {
+		case token.LBRACE, token.LPAREN, token.LBRACKET:
+			depth++
+		case token.RBRACE, token.RPAREN, token.RBRACKET:
+			depth--
+		}
+	}
+	return depth > 0
+}
+
+// 1行を今のモードで処理する
+// 評価までエラーなく済んだソースは :save のために覚えておく
+func (r *repl) eval(line string) {
+	if r.run(line, lexer.New(line)) && r.mode == ModeEval {
+		r.accepted = append(r.accepted, line)
+	}
+}
+
+// ソースを今のモードで処理して、エラーがなければ true を返す
+func (r *repl) run(src string, l *lexer.Lexer) bool {
+	// monkey tokens と同じ表の形で出す
+	if r.mode == ModeTokens {
+		w := tabwriter.NewWriter(r.out, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "POS\tEND\tTYPE\tLITERAL")
+		for _, tok := range l.Tokens() {
+			fmt.Fprintf(w, "%d:%d\t%d:%d\t%s\t%q\n",
+				tok.Pos.Line, tok.Pos.Column, tok.End.Line, tok.End.Column, tok.Type, tok.Literal)
+		}
+		w.Flush()
+		return len(l.Errors()) == 0
+	}
+
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) != 0 {
+		io.WriteString(r.out, errs.Render(src))
+		return false
+	}
+
+	switch r.mode {
+	case ModeAST:
+		fmt.Fprintln(r.out, program.String())
+	case ModeSexpr:
+		fmt.Fprintln(r.out, astdump.Sexpr(program))
+	case ModeEval:
+		evaluated := evaluator.Eval(program, r.env)
+		if evaluated != nil {
+			fmt.Fprintln(r.out, evaluated.Inspect())
+		}
+		if _, ok := evaluated.(*object.Error); ok {
+			return false
 		}
 	}
+	return true
 }
@@ -0,0 +1,216 @@
+package repl
+
+import (
+	"bytes"
+	"monkey/object"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestModes(t *testing.T) {
+	tests := []struct {
+		mode     Mode
+		input    string
+		expected string
+	}{
+		{ModeEval, "let x = 2;\nx * 3", ">> >> 6\n>> "},
+		{ModeAST, "let x = 1 + 2 * 3;", ">> let x = (1 + (2 * 3));\n>> "},
+		{ModeSexpr, "let x = 1 + 2 * 3;", ">> (let x (+ 1 (* 2 3)))\n>> "},
+		{ModeTokens, "x+", ">> POS  END  TYPE   LITERAL\n" +
+			"1:1  1:2  IDENT  \"x\"\n" +
+			"1:2  1:3  +      \"+\"\n>> "},
+		{ModeEval, "let = 1;", ">> 1:5: error: expected next token to be IDENT, got = instead\nlet = 1;\n    ^\n>> "},
+		// :mode で途中から切り替えられる
+		{ModeEval, "1 + 2\n:mode sexpr\n1 + 2\n:mode\n:mode eval\n1 + 2",
+			">> 3\n>> mode: sexpr\n>> (+ 1 2)\n>> mode: sexpr\n>> mode: eval\n>> 3\n>> "},
+		{ModeEval, ":mode lisp\n:nope", ">> unknown mode \"lisp\" (want eval, tokens, ast or sexpr)\n>> unknown command :nope (type :help for a list)\n>> "},
+	}
+
+	for i, tt := range tests {
+		var out bytes.Buffer
+		Start(strings.NewReader(tt.input), &out, WithMode(tt.mode))
+
+		if out.String() != tt.expected {
+			t.Errorf("tests[%d] - output wrong.\nexpected=%q\ngot=%q", i, tt.expected, out.String())
+		}
+	}
+}
+
+func TestMultiLineInput(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"let add = fn(a, b) {\n  a + b\n};\nadd(1,\n2)",
+			">> .. .. >> .. 3\n>> ",
+		},
+		{
+			"[1,\n[2,\n3]]",
+			">> .. .. [1, [2, 3]]\n>> ",
+		},
+		// 文字列やコメントの中の括弧は数えない
+		{
+			"\"{\" // (\n",
+			">> {\n>> ",
+		},
+		// 閉じすぎはそのままパーサに渡す
+		{
+			"1 }",
+			">> 1:3: error: no prefix parse function for } found\n1 }\n  ^\n>> ",
+		},
+		// 閉じないまま終わったら、そこまでを評価してエラーを出す
+		{
+			"if (true) {\n1",
+			">> .. .. 2:2: error: expected next token to be }, got EOF instead\n1\n ^\n",
+		},
+	}
+
+	for i, tt := range tests {
+		var out bytes.Buffer
+		Start(strings.NewReader(tt.input), &out)
+
+		if out.String() != tt.expected {
+			t.Errorf("tests[%d] - output wrong.\nexpected=%q\ngot=%q", i, tt.expected, out.String())
+		}
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for _, mode := range []Mode{ModeEval, ModeTokens, ModeAST, ModeSexpr} {
+		got, err := ParseMode(mode.String())
+		if err != nil || got != mode {
+			t.Errorf("ParseMode(%q) wrong. expected=%d, got=%d (%v)", mode.String(), mode, got, err)
+		}
+	}
+}
+
+func TestMetaCommands(t *testing.T) {
+	dir := t.TempDir()
+	saved := filepath.Join(dir, "session.mk")
+
+	input := strings.Join([]string{
+		"let x = 1;",
+		"let y = x +;",     // 構文エラーは保存しない
+		"let z = x + true", // 実行時エラーも保存しない
+		"let add = fn(a, b) {",
+		"a + b };",
+		":env",
+		":save " + saved,
+		":reset",
+		":env",
+		"x",
+		":load " + saved,
+		"add(x, 2)",
+		":load",
+	}, "\n")
+
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+
+	expected := ">> " +
+		">> 1:12: error: no prefix parse function for ; found\nlet y = x +;\n           ^\n" +
+		">> ERROR: type mismatch: INTEGER + BOOLEAN\n" +
+		">> .. " +
+		">> add = fn(a, b) { (a + b) }\nx = 1\n" +
+		">> saved 2 statements to " + saved + "\n" +
+		">> environment reset\n" +
+		">> " +
+		">> ERROR: identifier not found: x\n" +
+		">> " +
+		">> 3\n" +
+		">> usage: :load <file>\n" +
+		">> "
+	if out.String() != expected {
+		t.Errorf("output wrong.\nexpected=%q\ngot=%q", expected, out.String())
+	}
+
+	content, err := os.ReadFile(saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedContent := "let x = 1;\nlet add = fn(a, b) {\na + b };\n"
+	if string(content) != expectedContent {
+		t.Errorf("saved file wrong.\nexpected=%q\ngot=%q", expectedContent, string(content))
+	}
+}
+
+func TestEnvCommandWithNullBinding(t *testing.T) {
+	var out bytes.Buffer
+	Start(strings.NewReader("let y = fn() { let x = 1; }();\n:env"), &out)
+
+	expected := ">> >> y = null\n>> "
+	if out.String() != expected {
+		t.Errorf("output wrong.\nexpected=%q\ngot=%q", expected, out.String())
+	}
+
+	// 束縛に Go の nil が入っていても :env は落ちない
+	r := &repl{out: &out, env: object.NewEnvironment()}
+	r.env.Set("z", nil)
+	out.Reset()
+	r.listEnv(nil)
+	if out.String() != "z = null\n" {
+		t.Errorf("output wrong.\nexpected=%q\ngot=%q", "z = null\n", out.String())
+	}
+}
+
+func TestHelpCommand(t *testing.T) {
+	var out bytes.Buffer
+	Start(strings.NewReader(":help"), &out)
+
+	for _, c := range commands {
+		if !strings.Contains(out.String(), c.name) {
+			t.Errorf(":help does not mention %s. got=%q", c.name, out.String())
+		}
+	}
+}
+
+func TestHistoryFile(t *testing.T) {
+	history := filepath.Join(t.TempDir(), ".monkey_history")
+	if err := os.WriteFile(history, []byte("old\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	Start(strings.NewReader("1 + 2\n\n:env\nfn() {\n}"), &out, WithHistoryFile(history))
+
+	content, err := os.ReadFile(history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "old\n1 + 2\n:env\nfn() {\n}\n"
+	if string(content) != expected {
+		t.Errorf("history wrong.\nexpected=%q\ngot=%q", expected, string(content))
+	}
+}
+
+func TestIsCharDevice(t *testing.T) {
+	// キャラクタデバイスは、端末の設定が読めない環境では端末として扱う
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+
+	if !isCharDevice(devNull) {
+		t.Errorf("%s should be a character device", os.DevNull)
+	}
+
+	file, err := os.Create(filepath.Join(t.TempDir(), "input.mk"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if isCharDevice(file) {
+		t.Errorf("a regular file should not be a character device")
+	}
+	if IsTerminal(file) {
+		t.Errorf("a regular file should not be a terminal")
+	}
+	if IsTerminal(strings.NewReader("")) {
+		t.Errorf("strings.Reader should not be a terminal")
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package repl
+
+import (
+	"errors"
+	"os"
+)
+
+// Linux 以外では raw モードに対応していないので、行エディタは使わずいつも行単位で読む
+// 端末かどうかはキャラクタデバイスかどうかで判断する
+
+func isTerminal(f *os.File) bool {
+	return isCharDevice(f)
+}
+
+func makeRaw(fd uintptr) (restore func(), err error) {
+	return nil, errors.New("raw mode is not supported on this platform")
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package repl
+
+import (
+	"os"
+	"testing"
+)
+
+func TestCharDeviceIsTerminal(t *testing.T) {
+	// Linux 以外ではキャラクタデバイスを端末として扱う
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+
+	if !IsTerminal(devNull) {
+		t.Errorf("a character device should be treated as a terminal")
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"os"
+)
+
+// monkey run <file>
+func runScript(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprintln(stderr, "usage: monkey run <file>") }
+
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	filename := fs.Arg(0)
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitRuntimeError
+	}
+
+	return execute(filename, string(src), stdout, stderr, false)
+}
+
+// ソースコードを解析して実行し、終了コードを返す
+// printResult なら最後の値を出力する (NULL のときは何も出さない)
+func execute(filename, src string, stdout, stderr io.Writer, printResult bool) int {
+	l := lexer.New(src, lexer.WithFilename(filename))
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	if errs := p.Errors(); len(errs) > 0 {
+		fmt.Fprint(stderr, errs.Render(src))
+		return parseErrorCode(errs)
+	}
+
+	env := object.NewEnvironment()
+	result := evaluator.Eval(program, env)
+
+	if err, ok := result.(*object.Error); ok {
+		fmt.Fprintf(stderr, "%s: %s\n", filename, err.Message)
+		return exitRuntimeError
+	}
+
+	if printResult && result != nil && result != evaluator.NULL {
+		fmt.Fprintln(stdout, result.Inspect())
+	}
+	return exitOK
+}
+
+// 字句解析のエラーが含まれていれば exitLexError、そうでなければ exitParseError
+func parseErrorCode(errs parser.ErrorList) int {
+	for _, d := range errs {
+		if d.Code == parser.CodeLexError {
+			return exitLexError
+		}
+	}
+	return exitParseError
+}
@@ -0,0 +1,28 @@
+//go:build linux
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestRunDevNull(t *testing.T) {
+	// Linux では /dev/null は端末ではないので、REPLではなく空のスクリプトとして実行する
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+
+	var stdout, stderr bytes.Buffer
+	code := run(nil, devNull, &stdout, &stderr)
+
+	if code != exitOK {
+		t.Errorf("exit code wrong. expected=%d, got=%d (stderr=%q)", exitOK, code, stderr.String())
+	}
+	if stdout.Len() != 0 || stderr.Len() != 0 {
+		t.Errorf("unexpected output. stdout=%q, stderr=%q", stdout.String(), stderr.String())
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	script := writeSource(t, "#!/usr/bin/env monkey\nlet add = fn(a, b) { a + b };\nadd(1, 2);\n")
+	lexError := writeSource(t, "let s = \"abc;\n")
+	parseError := writeSource(t, "let = 5;\n")
+	runtimeError := writeSource(t, "let x = 1;\nx + true;\n")
+
+	tests := []struct {
+		args           []string
+		stdin          string
+		expectedCode   int
+		expectedStdout string
+		expectedStderr string
+	}{
+		// スクリプトの最後の値は出力しない
+		{[]string{"run", script}, "", exitOK, "", ""},
+		{[]string{"run", lexError}, "", exitLexError, "", "string literal not terminated"},
+		{[]string{"run", parseError}, "", exitParseError, "", "expected next token to be IDENT"},
+		{[]string{"run", runtimeError}, "", exitRuntimeError, "", "type mismatch: INTEGER + BOOLEAN"},
+		{[]string{"run", script + ".missing"}, "", exitRuntimeError, "", "no such file"},
+		{[]string{"run"}, "", exitUsage, "", "usage: monkey run <file>"},
+		{[]string{"-e", "1 + 2 * 3"}, "", exitOK, "7\n", ""},
+		{[]string{"-e", `"a" + "b"`}, "", exitOK, "ab\n", ""},
+		{[]string{"-e", "if (false) { 1 }"}, "", exitOK, "", ""},
+		{[]string{"-e", "1 +"}, "", exitParseError, "", "<expr>:1:4"},
+		{[]string{"-e", "-true"}, "", exitRuntimeError, "", "unknown operator: -BOOLEAN"},
+		// パイプで渡されたソースはスクリプトとして実行する
+		{nil, "#!/usr/bin/env monkey\nlet x = 1;\nx;\n", exitOK, "", ""},
+		{nil, "let x = 1;\ny;\n", exitRuntimeError, "", "<stdin>: identifier not found: y"},
+		{[]string{"nope"}, "", exitUsage, "", `unknown command "nope"`},
+		{[]string{"-x"}, "", exitUsage, "", "flag provided but not defined"},
+		{[]string{"--mode=lisp"}, "", exitUsage, "", `unknown mode "lisp"`},
+	}
+
+	for i, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		code := run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+
+		if code != tt.expectedCode {
+			t.Errorf("tests[%d] %q - exit code wrong. expected=%d, got=%d (stderr=%q)",
+				i, tt.args, tt.expectedCode, code, stderr.String())
+		}
+
+		if stdout.String() != tt.expectedStdout {
+			t.Errorf("tests[%d] %q - stdout wrong. expected=%q, got=%q",
+				i, tt.args, tt.expectedStdout, stdout.String())
+		}
+
+		if tt.expectedStderr == "" && stderr.Len() != 0 {
+			t.Errorf("tests[%d] %q - unexpected stderr: %q", i, tt.args, stderr.String())
+		}
+		if !strings.Contains(stderr.String(), tt.expectedStderr) {
+			t.Errorf("tests[%d] %q - stderr wrong. expected to contain %q, got=%q",
+				i, tt.args, tt.expectedStderr, stderr.String())
+		}
+	}
+}
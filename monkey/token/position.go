@@ -0,0 +1,33 @@
+package token
+
+import "fmt"
+
+// ソースコード中の位置
+// エラー表示やエディタ連携で使う
+type Position struct {
+	Filename string `json:"filename,omitempty"` // ファイル名 (無ければ空)
+	Offset   int    `json:"offset"`             // 先頭からのバイト数 (0始まり)
+	Line     int    `json:"line"`               // 行番号 (1始まり)
+	Column   int    `json:"column"`             // 列番号 (1始まり)
+}
+
+// Line が 0 のものは位置情報を持っていない
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// file:line:column の形式で返す
+// ファイル名が無ければ line:column になる
+func (p Position) String() string {
+	s := p.Filename
+	if p.IsValid() {
+		if s != "" {
+			s += ":"
+		}
+		s += fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	if s == "" {
+		s = "-"
+	}
+	return s
+}
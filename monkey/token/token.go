@@ -1,12 +1,29 @@
 package token
 
+import "sort"
+
 // string型からTokenType型を作る
 type TokenType string
 
 // TypeとLiteral属性を持ったToken型を作る
+// Pos は最初の文字の位置、End は最後の文字の直後の位置
 type Token struct {
-	Type    TokenType
-	Literal string
+	Type    TokenType `json:"type"`
+	Literal string    `json:"literal"`
+	Pos     Position  `json:"pos"`
+	End     Position  `json:"end"`
+
+	// コメントをトークンに付けて残す設定のときだけ入る
+	Leading  []Comment `json:"leading,omitempty"`  // トークンの前に書かれたコメント
+	Trailing []Comment `json:"trailing,omitempty"` // トークンと同じ行の後ろに書かれたコメント
+}
+
+// コメント1つ分
+// Text は // や /* */ も含めたそのままの文字列
+type Comment struct {
+	Text string   `json:"text"`
+	Pos  Position `json:"pos"`
+	End  Position `json:"end"`
 }
 
 // TokenTypeの種類を列挙する
@@ -14,9 +31,12 @@ type Token struct {
 const (
 	ILLEGAL = "ILLEGAL"
 	EOF     = "EOF"
+	COMMENT = "COMMENT"
 
-	IDENT = "IDENT" // 識別子: 変数名のこと
-	INT   = "INT"
+	IDENT  = "IDENT" // 識別子: 変数名のこと
+	INT    = "INT"
+	FLOAT  = "FLOAT"
+	STRING = "STRING"
 
 	ASSIGN   = "="
 	PLUS     = "+"
@@ -24,20 +44,30 @@ const (
 	BANG     = "!"
 	ASTERISK = "*"
 	SLASH    = "/"
+	PERCENT  = "%"
 
 	LT     = "<"
 	GT     = ">"
+	LT_EQ  = "<="
+	GT_EQ  = ">="
 	EQ     = "=="
 	NOT_EQ = "!="
 
+	AND = "&&"
+	OR  = "||"
+
 	COMMA     = ","
 	SEMICOLON = ";"
+	COLON     = ":"
 
 	LPAREN = "("
 	RPAREN = ")"
 	LBRACE = "{"
 	RBRACE = "}"
 
+	LBRACKET = "["
+	RBRACKET = "]"
+
 	// キーワード: 予約語
 	FUNCTION = "FUNCTION"
 	LET      = "LET"
@@ -58,6 +88,16 @@ var keywords = map[string]TokenType{
 	"false":  FALSE,
 }
 
+// キーワードの一覧を辞書順で返す (REPLの補完などに使う)
+func Keywords() []string {
+	words := make([]string, 0, len(keywords))
+	for word := range keywords {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+}
+
 func LookupIdent(ident string) TokenType {
 	if tok, ok := keywords[ident]; ok {
 		return tok
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"monkey/lexer"
+	"os"
+	"text/tabwriter"
+)
+
+const tokensUsage = "usage: monkey tokens [--format=table|json] <file>"
+
+// monkey tokens <file>
+// ファイルを字句解析して、トークンを表か1行1つのJSONで出力する
+// 字句のエラーがあれば stderr に出して exitLexError を返す
+func runTokens(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("tokens", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, tokensUsage)
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", "table", "output format: table or json")
+
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	if *format != "table" && *format != "json" {
+		fmt.Fprintf(stderr, "unknown format %q\n%s\n", *format, tokensUsage)
+		return exitUsage
+	}
+
+	filename := fs.Arg(0)
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitRuntimeError
+	}
+
+	tokens, errs := lexer.Tokenize(string(src), lexer.WithFilename(filename))
+
+	switch *format {
+	case "table":
+		w := tabwriter.NewWriter(stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "POS\tEND\tTYPE\tLITERAL")
+		for _, tok := range tokens {
+			fmt.Fprintf(w, "%d:%d\t%d:%d\t%s\t%q\n",
+				tok.Pos.Line, tok.Pos.Column, tok.End.Line, tok.End.Column, tok.Type, tok.Literal)
+		}
+		w.Flush()
+	case "json":
+		enc := json.NewEncoder(stdout)
+		for _, tok := range tokens {
+			enc.Encode(tok)
+		}
+	}
+
+	for _, err := range errs {
+		fmt.Fprintln(stderr, err)
+	}
+	if len(errs) > 0 {
+		return exitLexError
+	}
+	return exitOK
+}
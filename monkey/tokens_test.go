@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSource(t *testing.T, src string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.mk")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTokensTable(t *testing.T) {
+	path := writeSource(t, "let x = \"a\";")
+
+	var stdout, stderr bytes.Buffer
+	code := runTokens([]string{path}, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("exit code wrong. expected=%d, got=%d (%s)", exitOK, code, stderr.String())
+	}
+
+	expected := `POS   END   TYPE    LITERAL
+1:1   1:4   LET     "let"
+1:5   1:6   IDENT   "x"
+1:7   1:8   =       "="
+1:9   1:12  STRING  "a"
+1:12  1:13  ;       ";"
+`
+	if stdout.String() != expected {
+		t.Errorf("output wrong.\nexpected=\n%s\ngot=\n%s", expected, stdout.String())
+	}
+}
+
+func TestTokensJSON(t *testing.T) {
+	path := writeSource(t, "x & y")
+
+	var stdout, stderr bytes.Buffer
+	code := runTokens([]string{"--format=json", path}, &stdout, &stderr)
+	if code != exitLexError {
+		t.Fatalf("exit code wrong. expected=%d, got=%d", exitLexError, code)
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("wrong number of lines. expected=3, got=%d (%s)", len(lines), stdout.String())
+	}
+
+	expected := `{"type":"IDENT","literal":"x","pos":{"filename":"` + path + `","offset":0,"line":1,"column":1},"end":{"filename":"` + path + `","offset":1,"line":1,"column":2}}`
+	if lines[0] != expected {
+		t.Errorf("lines[0] wrong.\nexpected=%s\ngot=%s", expected, lines[0])
+	}
+
+	if !strings.Contains(stderr.String(), "illegal character '&'") {
+		t.Errorf("lexer error not reported. got=%q", stderr.String())
+	}
+}
+
+func TestTokensUsage(t *testing.T) {
+	tests := [][]string{
+		{},
+		{"--format=xml", "a.mk"},
+		{"a.mk", "b.mk"},
+	}
+
+	for _, args := range tests {
+		var stdout, stderr bytes.Buffer
+		if code := runTokens(args, &stdout, &stderr); code != exitUsage {
+			t.Errorf("runTokens(%q) exit code wrong. expected=%d, got=%d", args, exitUsage, code)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package width
+
+// width は文字列を端末で表示したときの幅 (マスの数) を数える
+// 漢字やかななどの東アジアの全角文字は2マス、それ以外は1マスとする
+
+// 1文字の幅
+func Rune(r rune) int {
+	if IsWide(r) {
+		return 2
+	}
+	return 1
+}
+
+// 文字列の幅
+func String(s string) int {
+	w := 0
+	for _, r := range s {
+		w += Rune(r)
+	}
+	return w
+}
+
+// rune の列の幅
+func Runes(rs []rune) int {
+	w := 0
+	for _, r := range rs {
+		w += Rune(r)
+	}
+	return w
+}
+
+// 全角 (2マス使う) 文字なら true
+func IsWide(r rune) bool {
+	return (r >= 0x1100 && r <= 0x115F) ||
+		(r >= 0x2E80 && r <= 0xA4CF && r != 0x303F) ||
+		(r >= 0xAC00 && r <= 0xD7A3) ||
+		(r >= 0xF900 && r <= 0xFAFF) ||
+		(r >= 0xFE30 && r <= 0xFE4F) ||
+		(r >= 0xFF00 && r <= 0xFF60) ||
+		(r >= 0xFFE0 && r <= 0xFFE6) ||
+		(r >= 0x1F300 && r <= 0x1F64F) ||
+		(r >= 0x1F900 && r <= 0x1F9FF) ||
+		(r >= 0x20000 && r <= 0x3FFFD)
+}
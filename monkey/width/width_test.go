@@ -0,0 +1,29 @@
+package width
+
+import "testing"
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int
+	}{
+		{"", 0},
+		{"let x", 5},
+		{"変数", 4},
+		{"かなカナ", 8},
+		{"한글", 4},
+		{"ＡＢ", 4},
+		{"é", 1},
+		{"😀!", 3},
+		{"let 変数 = ;", 12},
+	}
+
+	for _, tt := range tests {
+		if got := String(tt.input); got != tt.expected {
+			t.Errorf("String(%q) wrong. expected=%d, got=%d", tt.input, tt.expected, got)
+		}
+		if got := Runes([]rune(tt.input)); got != tt.expected {
+			t.Errorf("Runes(%q) wrong. expected=%d, got=%d", tt.input, tt.expected, got)
+		}
+	}
+}